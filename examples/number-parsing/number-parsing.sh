@@ -4,7 +4,7 @@ $ go run number-parsing.go
 456
 789
 135
-strconv.ParseInt: parsing "wat": invalid syntax
+strconv.Atoi: parsing "wat": invalid syntax
 
 # Далее рассмотрим другую распространённую
 # задачу парсинга: URL.
@@ -1,3 +1,3 @@
 $ go run sorting-by-functions.go 
-[киви персик банан]
+[киви банан персик]
 [{TJ 25} {Jax 37} {Alex 72}]
@@ -6,7 +6,7 @@ Thu 05 May 2022 10:10:12 PM PDT
 
 # date не имеет флага `-x`, поэтому завершится
 # с сообщением об ошибке и ненулевым кодом возврата.
-command exited with rc = 1
+command exit rc = 1
 > grep hello
 hello grep
 
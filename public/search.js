@@ -0,0 +1,177 @@
+/*
+* Search over the examples. tools/generate writes search.json next to the
+* index page: for every example its title and, for every segment, the
+* plain doc text and the identifiers used in its code.
+*
+* Words are compared by prefix after stripping common endings, so that
+* "горутины" finds "горутина" and "горутинами", and "timers" finds "timer".
+*/
+
+(function() {
+  var form = document.getElementById('search');
+  if (!form || !window.fetch) {
+    return;
+  }
+  var input = form.querySelector('input');
+  var results = form.querySelector('ol');
+  var examples = null;
+  form.hidden = false;
+
+  // Endings are tried longest first; a stem keeps at least three letters.
+  var ruEndings = [
+    'иями', 'ями', 'ами', 'ого', 'его', 'ому', 'ему', 'ыми', 'ими', 'ией',
+    'ешь', 'ете', 'ишь', 'ите', 'ться', 'тся', 'ать', 'ять', 'ить', 'еть',
+    'ые', 'ие', 'ой', 'ей', 'ий', 'ый', 'ая', 'яя', 'ое', 'ее', 'ую', 'юю',
+    'ам', 'ям', 'ах', 'ях', 'ом', 'ем', 'ов', 'ев', 'ию', 'ия', 'ть', 'ся',
+    'сь', 'ет', 'ют', 'ут', 'ит', 'ат', 'ят', 'ло', 'ла', 'ли',
+    'а', 'я', 'о', 'е', 'ы', 'и', 'у', 'ю', 'ь', 'й'
+  ].sort(function(a, b) { return b.length - a.length; });
+  var enEndings = ['ing', 'ies', 'es', 'ed', 's'];
+
+  function stem(word) {
+    if (word.indexOf('.') >= 0) {
+      return word;
+    }
+    word = word.replace(/ё/g, 'е');
+    var endings = /[а-я]/.test(word) ? ruEndings : enEndings;
+    for (var i = 0; i < endings.length; i++) {
+      var e = endings[i];
+      if (word.length - e.length >= 3 && word.slice(-e.length) === e) {
+        return word.slice(0, -e.length);
+      }
+    }
+    return word;
+  }
+
+  function words(text) {
+    return text.toLowerCase().match(/[\p{L}\p{N}_]+(\.[\p{L}\p{N}_]+)*/gu) || [];
+  }
+
+  function stems(text) {
+    return words(text).map(stem);
+  }
+
+  // identWords splits time.Tick into "time.tick", "time" and "tick", so
+  // that each of them finds it.
+  function identWords(idents) {
+    var all = [];
+    (idents || []).forEach(function(ident) {
+      ident = ident.toLowerCase();
+      all.push(ident);
+      if (ident.indexOf('.') >= 0) {
+        all.push.apply(all, ident.split('.'));
+      }
+    });
+    return all;
+  }
+
+  function prepare(index) {
+    return index.map(function(example) {
+      return {
+        id: example.id,
+        title: example.t,
+        titleWords: stems(example.t),
+        segs: (example.s || []).map(function(seg) {
+          return {
+            anchor: seg.a,
+            text: seg.d || '',
+            idents: seg.i || [],
+            words: stems(seg.d || ''),
+            identWords: identWords(seg.i)
+          };
+        })
+      };
+    });
+  }
+
+  function hasPrefix(list, term) {
+    for (var i = 0; i < list.length; i++) {
+      if (list[i].lastIndexOf(term, 0) === 0) {
+        return list[i] === term ? 2 : 1;
+      }
+    }
+    return 0;
+  }
+
+  // search ranks segments by how well they match every term: identifiers
+  // weigh most, then title words, then doc words. A term that only
+  // matches the title still lets the segments of that example through,
+  // and an example whose title matches every term is found by itself.
+  function search(query) {
+    var terms = stems(query);
+    var found = [];
+    if (terms.length === 0) {
+      return found;
+    }
+    examples.forEach(function(example) {
+      var titleScore = 0;
+      var titleHits = terms.map(function(term) {
+        var m = hasPrefix(example.titleWords, term);
+        titleScore += m * 2;
+        return m > 0;
+      });
+      var hits = 0;
+      example.segs.forEach(function(seg) {
+        var score = titleScore;
+        for (var i = 0; i < terms.length; i++) {
+          var ident = hasPrefix(seg.identWords, terms[i]);
+          var doc = hasPrefix(seg.words, terms[i]);
+          if (!ident && !doc && !titleHits[i]) {
+            return;
+          }
+          score += ident * 3 + doc;
+        }
+        if (score > titleScore) {
+          found.push({example: example, seg: seg, score: score});
+          hits++;
+        }
+      });
+      if (hits === 0 && titleHits.every(Boolean)) {
+        found.push({example: example, seg: null, score: titleScore + 1});
+      }
+    });
+    found.sort(function(a, b) { return b.score - a.score; });
+    return found.slice(0, 20);
+  }
+
+  function snippet(seg) {
+    var text = seg.text || seg.idents.join(' ');
+    return text.length > 120 ? text.slice(0, 117) + '…' : text;
+  }
+
+  function render() {
+    results.textContent = '';
+    search(input.value).forEach(function(hit) {
+      var li = document.createElement('li');
+      var a = document.createElement('a');
+      a.href = hit.example.id + (hit.seg ? '#' + hit.seg.anchor : '');
+      a.textContent = hit.example.title;
+      li.appendChild(a);
+      if (hit.seg) {
+        li.appendChild(document.createTextNode(' — ' + snippet(hit.seg)));
+      }
+      results.appendChild(li);
+    });
+  }
+
+  function load() {
+    if (examples) {
+      return Promise.resolve();
+    }
+    return fetch('search.json', {cache: 'no-cache'})
+      .then(function(resp) { return resp.json(); })
+      .then(function(index) { examples = prepare(index); });
+  }
+
+  input.addEventListener('focus', load);
+  input.addEventListener('input', function() {
+    load().then(render);
+  });
+  form.addEventListener('submit', function(e) {
+    e.preventDefault();
+    var first = results.querySelector('a');
+    if (first) {
+      window.location.href = first.href;
+    }
+  });
+})();
@@ -0,0 +1,163 @@
+package site
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// setAnchors gives every segment of an example the ID of its row on the
+// page. Anchors are derived from what the segments contain rather than
+// where they are, so that links to a segment survive edits elsewhere in
+// the example, and as they come from the code where there is any, they are
+// the same in every locale. A repeated anchor gets the next numeric suffix
+// that no other segment has, as does one that would clash with the
+// example's ID.
+func setAnchors(id string, segs [][]*Seg) {
+	taken := map[string]bool{id: true}
+	suffixes := make(map[string]int)
+	for _, sourceSegs := range segs {
+		for _, seg := range sourceSegs {
+			name := segAnchor(seg)
+			anchor := name
+			for taken[anchor] {
+				suffixes[name]++
+				anchor = fmt.Sprintf("%s-%d", name, suffixes[name]+1)
+			}
+			taken[anchor] = true
+			seg.Anchor = anchor
+		}
+	}
+}
+
+// segAnchors returns the anchors of an example's segments in order.
+func segAnchors(segs [][]*Seg) []string {
+	var anchors []string
+	for _, sourceSegs := range segs {
+		for _, seg := range sourceSegs {
+			anchors = append(anchors, seg.Anchor)
+		}
+	}
+	return anchors
+}
+
+// segAnchor returns the anchor of a segment before deduplication: a name
+// from its Go code or the command of its shell session, or else a short
+// hash of its docs.
+func segAnchor(seg *Seg) string {
+	code := strings.TrimSpace(seg.Code)
+	name := ""
+	switch {
+	case code == "":
+	case seg.goCode:
+		name = goAnchor(code)
+	default:
+		name = shellAnchor(code)
+	}
+	if name != "" {
+		return name
+	}
+	text := seg.Docs
+	if text == "" {
+		text = seg.Code
+	}
+	return "s-" + sha1Sum(text)[:7]
+}
+
+type goToken struct {
+	tok token.Token
+	lit string
+}
+
+// goAnchor names a fragment of Go code after what it's about: the package
+// or import clause it is, the first name it declares or assigns, or else
+// the first identifier or keyword in it.
+func goAnchor(code string) string {
+	var s scanner.Scanner
+	src := []byte(code)
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", -1, len(src)), src, nil, 0)
+	var toks []goToken
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		toks = append(toks, goToken{tok, lit})
+	}
+	if len(toks) == 0 {
+		return ""
+	}
+	if first := toks[0].tok; first == token.PACKAGE || first == token.IMPORT {
+		return first.String()
+	}
+
+	ident := func(i int) string {
+		if i >= 0 && i < len(toks) && toks[i].tok == token.IDENT && toks[i].lit != "_" {
+			return toks[i].lit
+		}
+		return ""
+	}
+	for i, t := range toks {
+		name := ""
+		switch t.tok {
+		case token.FUNC:
+			// Skip the receiver of a method.
+			j := i + 1
+			if j < len(toks) && toks[j].tok == token.LPAREN {
+				for j < len(toks) && toks[j].tok != token.RPAREN {
+					j++
+				}
+				j++
+			}
+			name = ident(j)
+		case token.TYPE, token.VAR, token.CONST:
+			name = ident(i + 1)
+		case token.DEFINE, token.ASSIGN:
+			name = ident(i - 1)
+		}
+		if name != "" {
+			return anchorSlug(name)
+		}
+	}
+	if idents := codeIdents(code, nil); len(idents) > 0 {
+		return anchorSlug(idents[0])
+	}
+	for _, t := range toks {
+		if t.tok.IsKeyword() {
+			return t.tok.String()
+		}
+	}
+	return ""
+}
+
+// shellAnchor names a shell session after the first words of its first
+// command, or returns "" if it has none.
+func shellAnchor(code string) string {
+	for _, line := range strings.Split(code, "\n") {
+		command, ok := strings.CutPrefix(strings.TrimSpace(line), "$")
+		if !ok {
+			continue
+		}
+		words := strings.Fields(command)
+		if len(words) > 3 {
+			words = words[:3]
+		}
+		return anchorSlug(strings.Join(words, "-"))
+	}
+	return ""
+}
+
+// anchorSlug replaces the characters of s that don't read well in a URL
+// fragment with dashes.
+func anchorSlug(s string) string {
+	slug := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+	return strings.Trim(dashPat.ReplaceAllString(slug, "-"), "-.")
+}
@@ -0,0 +1,65 @@
+package site
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoAnchor(t *testing.T) {
+	tests := []struct {
+		code, want string
+	}{
+		{"package main", "package"},
+		{"import (\n\t\"fmt\"\n)", "import"},
+		{"func main() {", "main"},
+		{"func (r rect) area() float64 {", "area"},
+		{"type point struct {", "point"},
+		{"\tx := 1", "x"},
+		{"\t_, err = f()", "err"},
+		{"\tfmt.Println(\"hi\")", "fmt.Println"},
+		{"\t}", ""},
+	}
+	for _, tt := range tests {
+		if got := goAnchor(tt.code); got != tt.want {
+			t.Errorf("goAnchor(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestSetAnchors(t *testing.T) {
+	goSeg := func(code string) *Seg { return &Seg{Code: code, goCode: true} }
+	tests := []struct {
+		id    string
+		codes []string
+		want  string
+	}{
+		{"hello", []string{"x := 1", "y := 2", "x = 3", "x = 4"}, "x y x-2 x-3"},
+		{"x", []string{"x := 1", "x = 2"}, "x-2 x-3"},
+	}
+	for _, tt := range tests {
+		var segs []*Seg
+		for _, code := range tt.codes {
+			segs = append(segs, goSeg(code))
+		}
+		setAnchors(tt.id, [][]*Seg{segs})
+		got := strings.Join(segAnchors([][]*Seg{segs}), " ")
+		if got != tt.want {
+			t.Errorf("setAnchors(%q, %q) = %s, want %s", tt.id, tt.codes, got, tt.want)
+		}
+	}
+
+	// An anchor from a command can end in what looks like a suffix, which
+	// the repeated anchors after it skip.
+	segs := []*Seg{{Code: "$ go run x-2"}, {Code: "$ go run x"}, {Code: "$ go run x"}, {Code: "$ go run x"}}
+	setAnchors("hello", [][]*Seg{segs})
+	if got, want := strings.Join(segAnchors([][]*Seg{segs}), " "), "go-run-x-2 go-run-x go-run-x-3 go-run-x-4"; got != want {
+		t.Errorf("setAnchors with a literal suffix = %s, want %s", got, want)
+	}
+	seen := make(map[string]bool)
+	for _, seg := range segs {
+		if seen[seg.Anchor] {
+			t.Errorf("anchor %s is used twice", seg.Anchor)
+		}
+		seen[seg.Anchor] = true
+	}
+}
@@ -0,0 +1,134 @@
+package site
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// APIIndexFile is the page of every locale that lists the packages,
+// builtins and keywords the examples use, see APIIndexData.
+const APIIndexFile = "api"
+
+// apiIndex collects what the examples use from the standard library and
+// the language, and the segments that use it. The sources of examples that
+// weren't parsed are read again. Without a GOROOT to type check them with,
+// the index is empty.
+func (b *builder) apiIndex(examples []*Example) *APIIndexData {
+	type apiKey struct{ path, key string }
+	var order []apiKey
+	uses := make(map[apiKey][]*APIUse)
+	titles := make(map[apiKey]string)
+
+	for _, example := range examples {
+		segs := example.Segs
+		if segs == nil {
+			segs = b.readSegs(example)
+		}
+		dir := b.exampleDir(example.ID)
+		var goFiles []playgroundFile
+		var goSegs [][]*Seg
+		for i, path := range b.sourcePaths(dir) {
+			if filepath.Ext(path) == ".go" && i < len(segs) {
+				goFiles = append(goFiles, playgroundFile{filepath.Base(path), strings.Join(b.readLines(path), "\n")})
+				goSegs = append(goSegs, segs[i])
+			}
+		}
+		if len(goFiles) == 0 {
+			continue
+		}
+		info := b.analyzeGo(dir, goFiles)
+		if info == nil {
+			return &APIIndexData{}
+		}
+
+		// The places of the segments among the example's, from 1.
+		places := make(map[*Seg]int)
+		for _, sourceSegs := range segs {
+			for _, seg := range sourceSegs {
+				places[seg] = len(places) + 1
+			}
+		}
+		found := make(map[apiKey]*APIUse)
+		for i, f := range goFiles {
+			lines := make(map[int]*Seg)
+			for _, seg := range goSegs[i] {
+				for _, line := range seg.codeLines {
+					lines[line] = seg
+				}
+			}
+			for k, refs := range [][]stdRef{info.refs[f.name], info.others[f.name]} {
+				for _, ref := range refs {
+					seg := lines[ref.line]
+					// Packages are listed with the segments that import
+					// them, rather than those that qualify names by them.
+					if seg == nil || k == 0 && ref.key == "" {
+						continue
+					}
+					key := apiKey{ref.path, ref.key}
+					use := found[key]
+					if use == nil {
+						use = &APIUse{Example: example}
+						found[key] = use
+						if uses[key] == nil {
+							order = append(order, key)
+							titles[key] = ref.title
+						}
+						uses[key] = append(uses[key], use)
+					}
+					use.Segs = append(use.Segs, APISeg{seg.Anchor, places[seg]})
+				}
+			}
+		}
+		for _, use := range found {
+			sort.Slice(use.Segs, func(i, j int) bool { return use.Segs[i].N < use.Segs[j].N })
+			use.Segs = slices.Compact(use.Segs)
+		}
+	}
+
+	data := &APIIndexData{}
+	packages := make(map[string]*APIPackage)
+	packageOf := func(path string) *APIPackage {
+		if packages[path] == nil {
+			packages[path] = &APIPackage{Path: path, DocURL: docURL(path, ""), Synopsis: b.goCheck.synopsis(path, "")}
+			data.Packages = append(data.Packages, packages[path])
+		}
+		return packages[path]
+	}
+	for _, key := range order {
+		switch {
+		case key.path == "":
+			data.Keywords = append(data.Keywords, &APIName{Name: key.key, ID: "keyword-" + key.key, Uses: uses[key]})
+		case key.key == "":
+			packageOf(key.path).Imports = uses[key]
+		default:
+			pkg := packageOf(key.path)
+			pkg.Names = append(pkg.Names, &APIName{
+				Name:     key.key,
+				ID:       key.path + "." + key.key,
+				DocURL:   docURL(key.path, key.key),
+				Synopsis: titles[key],
+				Uses:     uses[key],
+			})
+		}
+	}
+	sort.Slice(data.Packages, func(i, j int) bool { return data.Packages[i].Path < data.Packages[j].Path })
+	for _, pkg := range data.Packages {
+		sort.Slice(pkg.Names, func(i, j int) bool { return pkg.Names[i].Name < pkg.Names[j].Name })
+	}
+	sort.Slice(data.Keywords, func(i, j int) bool { return data.Keywords[i].Name < data.Keywords[j].Name })
+	return data
+}
+
+// renderAPIIndex renders the API index of examples.
+func (b *builder) renderAPIIndex(examples []*Example, site *SiteConfig, outDir string) {
+	b.logf("Rendering API index")
+	tmpl := b.parseTemplates("api", "footer.tmpl", "api.tmpl")
+	if tmpl == nil {
+		return
+	}
+	data := b.apiIndex(examples)
+	data.Site = site
+	b.renderPage(tmpl, b.templatePath("api.tmpl"), outDir, APIIndexFile, data)
+}
@@ -0,0 +1,60 @@
+package site
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAPIIndex(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root,
+		"examples.txt", "hello|Hello\nwait|Wait\n",
+		"examples/hello/hello.go", "package main\n\nimport \"fmt\"\n\n"+
+			"// Print.\nfunc main() {\n\tfmt.Println(len(\"hi\"))\n}\n",
+		"examples/wait/wait.go", "package main\n\nimport (\n\t\"fmt\"\n\t\"time\"\n)\n\n"+
+			"func main() {\n\tc := make(chan int)\n\n"+
+			"\t// Wait.\n\tselect {\n\tcase <-c:\n\tcase <-time.After(time.Second):\n\t\tfmt.Println(\"timeout\")\n\t}\n}\n")
+	b := newBuilder(Config{Root: root, Settings: DefaultSettings()})
+	data := b.apiIndex(b.readExampleList())
+	if len(data.Packages) == 0 {
+		t.Skip("no GOROOT to type check with")
+	}
+
+	var got []string
+	uses := func(uses []*APIUse) string {
+		var s []string
+		for _, use := range uses {
+			for _, seg := range use.Segs {
+				s = append(s, fmt.Sprintf("%s#%s(%d)", use.Example.ID, seg.Anchor, seg.N))
+			}
+		}
+		return strings.Join(s, " ")
+	}
+	for _, pkg := range data.Packages {
+		got = append(got, pkg.Path+": "+uses(pkg.Imports))
+		for _, name := range pkg.Names {
+			got = append(got, name.ID+": "+uses(name.Uses))
+		}
+	}
+	for _, kw := range data.Keywords {
+		got = append(got, kw.ID+": "+uses(kw.Uses))
+	}
+	want := []string{
+		"builtin: ",
+		"builtin.int: wait#main(3)",
+		"builtin.len: hello#main(3)",
+		"builtin.make: wait#main(3)",
+		"fmt: hello#import(2) wait#import(2)",
+		"fmt.Println: hello#main(3) wait#time.After(4)",
+		"time: wait#import(2)",
+		"time.After: wait#time.After(4)",
+		"time.Second: wait#time.After(4)",
+		"keyword-case: wait#time.After(4)",
+		"keyword-chan: wait#main(3)",
+		"keyword-select: wait#time.After(4)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("API index:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,317 @@
+package site
+
+import (
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BookFile and EPUBFile are the files that Export writes for the "book"
+// format: every example on one printable page, and an EPUB 3 book.
+const (
+	BookFile = "book.html"
+	EPUBFile = "book.epub"
+)
+
+// ExportFormats are the formats Export can write.
+var ExportFormats = []string{"book", "markdown", "ipynb"}
+
+// epubContainer points reading systems to the package document.
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// hrefPat matches the href attributes of rendered docs.
+var hrefPat = regexp.MustCompile(`\shref="([^"]*)"`)
+
+// entityPat matches the named character references of rendered docs, which
+// XHTML only has the XML ones of.
+var entityPat = regexp.MustCompile(`&[A-Za-z][A-Za-z0-9]*;`)
+
+// Export writes the examples of every locale in format, one of
+// ExportFormats, into the locale's directory below outDir like Build. The
+// examples are parsed in the order of examples.txt; nothing is written for
+// a locale whose examples have errors. Exports have no run links, so
+// nothing is shared with the playground.
+func Export(cfg Config, format, outDir string) error {
+	cfg.Share = OfflineShare{}
+	b := newBuilder(cfg)
+	if !slices.Contains(ExportFormats, format) {
+		b.diags.Errorf("", 0, "unknown export format %q, expected one of %s", format, strings.Join(ExportFormats, ", "))
+		return b.err()
+	}
+	for _, lb := range b.locales() {
+		examples := lb.readExampleList()
+		lb.parseSelected(examples)
+		if b.diags.Errors() > 0 {
+			break
+		}
+		dir := filepath.Join(outDir, lb.locale.Path)
+		if b.failed(dir, os.MkdirAll(dir, 0755)) {
+			break
+		}
+		switch format {
+		case "book":
+			lb.writeBook(examples, dir)
+			lb.writeEPUB(examples, dir)
+		case "markdown":
+			lb.writeMarkdown(examples, dir)
+		case "ipynb":
+			lb.writeNotebooks(examples, dir)
+		}
+	}
+	return b.err()
+}
+
+// bookRefs make the links between the parts of a book: within its single
+// page, or between the files of the EPUB book.
+type bookRefs struct {
+	epub bool
+}
+
+func (r bookRefs) chapter(id string) string {
+	if r.epub {
+		return id + ".xhtml"
+	}
+	return "#" + id
+}
+
+// segID returns the ID of a segment's row, which on the single page has
+// the example's ID in front of its anchor. Slugs never have a double dash.
+func (r bookRefs) segID(id, anchor string) string {
+	if r.epub {
+		return anchor
+	}
+	return id + "--" + anchor
+}
+
+func (r bookRefs) seg(id, anchor string) string {
+	if r.epub {
+		return r.chapter(id) + "#" + anchor
+	}
+	return "#" + r.segID(id, anchor)
+}
+
+// section links to the heading of a section, which in the EPUB book is on
+// the page of its first example.
+func (r bookRefs) section(s *Section) string {
+	if r.epub {
+		return r.chapter(s.Examples[0].ID)
+	}
+	return "#section-" + s.ID
+}
+
+func (r bookRefs) contents() string {
+	if r.epub {
+		return "nav.xhtml"
+	}
+	return "#contents"
+}
+
+// bookData arranges parsed examples into the sections and chapters of a
+// book.
+func (b *builder) bookData(examples []*Example, refs bookRefs) *BookData {
+	data := &BookData{Site: b.siteConfig()}
+	sections := listSections(examples)
+	if sections == nil {
+		sections = []*Section{{Examples: examples}}
+	}
+	listed := make(map[string]bool)
+	sectionRefs := make(map[string]string)
+	for _, s := range sections {
+		for _, example := range s.Examples {
+			listed[example.ID] = true
+		}
+		if s.ID != "" {
+			sectionRefs[s.ID] = refs.section(s)
+		}
+	}
+
+	for _, s := range sections {
+		section := &BookSection{Title: s.Title}
+		if s.ID != "" {
+			section.ID = "section-" + s.ID
+			section.Href = sectionRefs[s.ID]
+		}
+		for _, example := range s.Examples {
+			chapter := &BookChapter{Example: example, ID: example.ID, Href: refs.chapter(example.ID)}
+			for _, sourceSegs := range example.Segs {
+				var segs []*BookSeg
+				for _, seg := range sourceSegs {
+					docs := b.bookLinks(seg.DocsRendered, example.ID, refs, listed, sectionRefs)
+					code := seg.CodeRendered
+					if refs.epub {
+						docs = xmlEntities(docs)
+						code = ""
+						if seg.Code != "" {
+							code = b.chromaFormatWith(chromaInlineFormatter, seg.Code, bookSourcePath(b.exampleDir(example.ID), seg))
+						}
+					}
+					segs = append(segs, &BookSeg{ID: refs.segID(example.ID, seg.Anchor), Docs: docs, Code: code, CodeEmpty: seg.CodeEmpty})
+				}
+				chapter.Segs = append(chapter.Segs, segs)
+			}
+			section.Chapters = append(section.Chapters, chapter)
+		}
+		data.Sections = append(data.Sections, section)
+	}
+	return data
+}
+
+// bookSourcePath returns a path for the lexer of a segment's code, named
+// after the kind of source it comes from.
+func bookSourcePath(dir string, seg *Seg) string {
+	if seg.goCode {
+		return filepath.Join(dir, filepath.Base(dir)+".go")
+	}
+	return filepath.Join(dir, filepath.Base(dir)+".sh")
+}
+
+// bookLinks points the links of an example's rendered docs into the book:
+// links to segments, examples and sections of the index go to their place
+// in the book, and links to the other pages of the site to their absolute
+// URLs. Without a base URL to make those from, the link is dropped and
+// its text kept. Absolute links are left alone.
+func (b *builder) bookLinks(docs, id string, refs bookRefs, listed map[string]bool, sectionRefs map[string]string) string {
+	return hrefPat.ReplaceAllStringFunc(docs, func(attr string) string {
+		u, err := url.Parse(html.UnescapeString(hrefPat.FindStringSubmatch(attr)[1]))
+		if err != nil || u.IsAbs() || u.Host != "" {
+			return attr
+		}
+		target := ""
+		switch {
+		case u.Path == "" && u.Fragment != "":
+			target = refs.seg(id, u.Fragment)
+		case u.Path == "./" || u.Path == ".":
+			target = refs.contents()
+			if ref, ok := sectionRefs[u.Fragment]; ok {
+				target = ref
+			}
+		case listed[u.Path]:
+			target = refs.chapter(u.Path)
+			if u.Fragment != "" {
+				target = refs.seg(u.Path, u.Fragment)
+			}
+		default:
+			if target = b.pageURL(u.Path); target != "" && u.Fragment != "" {
+				target += "#" + u.Fragment
+			}
+		}
+		if target == "" {
+			return ""
+		}
+		return ` href="` + html.EscapeString(target) + `"`
+	})
+}
+
+// xmlEntities replaces the named character references of HTML with the
+// characters, but for those XML has too.
+func xmlEntities(s string) string {
+	return entityPat.ReplaceAllStringFunc(s, func(ref string) string {
+		switch ref {
+		case "&lt;", "&gt;", "&amp;", "&quot;", "&apos;":
+			return ref
+		}
+		return html.UnescapeString(ref)
+	})
+}
+
+// writeBook renders the examples into a single page with a table of
+// contents, styled for reading and for printing.
+func (b *builder) writeBook(examples []*Example, outDir string) {
+	b.logf("Rendering %s", BookFile)
+	tmpl := b.parseTemplates("book", "footer.tmpl", "book.tmpl")
+	if tmpl == nil {
+		return
+	}
+	data := b.bookData(examples, bookRefs{})
+	data.CSS = b.readFile("templates/book.css") + styleRules(chromaStyle(b.settings.Style), "")
+	b.renderPage(tmpl, b.templatePath("book.tmpl"), outDir, BookFile, data)
+}
+
+// writeEPUB writes the examples as an EPUB 3 book: an XHTML page per
+// example, with the colors of the code inline, the navigation document
+// with the table of contents, and the package document listing them.
+func (b *builder) writeEPUB(examples []*Example, outDir string) {
+	b.logf("Rendering %s", EPUBFile)
+	tmplPath := b.templatePath("epub.tmpl")
+	tmpl := b.parseTemplates("epub", "epub.tmpl")
+	if tmpl == nil {
+		return
+	}
+	data := b.bookData(examples, bookRefs{epub: true})
+	data.ID = nameUUID(b.locale.Title + ":" + b.locale.Language + ":book")
+	data.Modified = time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	type epubFile struct {
+		name, content string
+	}
+	files := []epubFile{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/style.css", b.readFile("templates/book.css")},
+	}
+	execute := func(name string, data any) string {
+		var buf bytes.Buffer
+		if b.templateFailed(tmplPath, tmpl.ExecuteTemplate(&buf, name, data), false) {
+			return ""
+		}
+		return buf.String()
+	}
+	for _, section := range data.Sections {
+		for _, chapter := range section.Chapters {
+			page := *data
+			page.Chapter = chapter
+			files = append(files, epubFile{"OEBPS/" + chapter.Href, execute("epub-chapter", &page)})
+		}
+	}
+	files = append(files,
+		epubFile{"OEBPS/nav.xhtml", execute("epub-nav", data)},
+		epubFile{"OEBPS/content.opf", execute("epub-opf", data)})
+	if b.diags.Errors() > 0 {
+		return
+	}
+
+	// The mimetype goes first and uncompressed, so that the type of the
+	// file can be told from its first bytes.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	mimetype := []byte("application/epub+zip")
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err == nil {
+		_, err = w.Write(mimetype)
+	}
+	for _, f := range files {
+		if err != nil {
+			break
+		}
+		if w, err = zw.Create(f.name); err == nil {
+			_, err = w.Write([]byte(f.content))
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	path := filepath.Join(outDir, EPUBFile)
+	if b.failed(path, err) {
+		return
+	}
+	b.failed(path, os.WriteFile(path, buf.Bytes(), 0644))
+}
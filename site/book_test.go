@@ -0,0 +1,111 @@
+package site
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBookLinks(t *testing.T) {
+	settings := DefaultSettings()
+	settings.BaseURL = "https://example.com/"
+	b := newBuilder(Config{Root: t.TempDir(), Settings: settings})
+	listed := map[string]bool{"timers": true}
+	sections := map[string]string{"basics": "#section-basics"}
+	docs := `<a href="timers">a</a> <a href="timers#wait">b</a> <a href="#loop">c</a> ` +
+		`<a href="./">d</a> <a href="./#basics">e</a> <a href="api#fmt">f</a> <a href="https://go.dev/">g</a>`
+	tests := []struct {
+		refs bookRefs
+		want string
+	}{
+		{bookRefs{}, `<a href="#timers">a</a> <a href="#timers--wait">b</a> <a href="#tickers--loop">c</a> ` +
+			`<a href="#contents">d</a> <a href="#section-basics">e</a> <a href="https://example.com/api#fmt">f</a> <a href="https://go.dev/">g</a>`},
+		{bookRefs{epub: true}, `<a href="timers.xhtml">a</a> <a href="timers.xhtml#wait">b</a> <a href="tickers.xhtml#loop">c</a> ` +
+			`<a href="nav.xhtml">d</a> <a href="#section-basics">e</a> <a href="https://example.com/api#fmt">f</a> <a href="https://go.dev/">g</a>`},
+	}
+	for _, tt := range tests {
+		if got := b.bookLinks(docs, "tickers", tt.refs, listed, sections); got != tt.want {
+			t.Errorf("bookLinks(epub=%v) =\n%s\nwant\n%s", tt.refs.epub, got, tt.want)
+		}
+	}
+
+	b.settings.BaseURL = ""
+	if got, want := b.bookLinks(`<a href="api">api</a>`, "tickers", bookRefs{}, listed, sections), `<a>api</a>`; got != want {
+		t.Errorf("bookLinks without a base URL = %s, want %s", got, want)
+	}
+}
+
+func TestExport(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root,
+		"examples.txt", "## Basics\nhello|Hello & bye\nbye|Bye\n",
+		"examples/hello/hello.go", "package main\n\n// Say \"hello\", then [bye](bye#main).\nfunc main() {}\n",
+		"examples/hello/hello.sh", "$ go run hello.go\n",
+		"examples/bye/bye.go", "// Back to [the start](./#basics).\npackage main\n\nfunc main() {}\n")
+	for _, name := range []string{"book.tmpl", "epub.tmpl", "footer.tmpl", "book.css", "site.css", "site.js", "search.js"} {
+		data, err := os.ReadFile(filepath.Join("..", "templates", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, root, "templates/"+name, string(data))
+	}
+	out := t.TempDir()
+	if err := Export(Config{Root: root, Settings: DefaultSettings()}, "book", out); err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := os.ReadFile(filepath.Join(out, BookFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`id="section-basics"`, `id="bye"`, `href="#bye--main"`, `Hello &amp; bye`} {
+		if !strings.Contains(string(book), want) {
+			t.Errorf("%s has no %s", BookFile, want)
+		}
+	}
+
+	r, err := zip.OpenReader(filepath.Join(out, EPUBFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if f := r.File[0]; f.Name != "mimetype" || f.Method != zip.Store {
+		t.Errorf("first entry is %s with method %d, want mimetype stored", f.Name, f.Method)
+	}
+	var names []string
+	for _, f := range r.File[1:] {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Ext(f.Name) == ".css" {
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(string(data)))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s: %v", f.Name, err)
+				break
+			}
+		}
+		if f.Name == "OEBPS/bye.xhtml" && !strings.Contains(string(data), `href="hello.xhtml"`) {
+			t.Errorf("%s doesn't link to the section's first example:\n%s", f.Name, data)
+		}
+	}
+	want := "META-INF/container.xml OEBPS/style.css OEBPS/hello.xhtml OEBPS/bye.xhtml OEBPS/nav.xhtml OEBPS/content.opf"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("EPUB files are %s, want %s", got, want)
+	}
+}
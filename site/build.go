@@ -0,0 +1,415 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestFile is the name of the build manifest that Build writes into the
+// output directory.
+const ManifestFile = "manifest.json"
+
+// Manifest records the hashes of the inputs of every generated file. The
+// next build compares against it to render only the pages whose inputs
+// changed, and to remove the pages of examples that are gone.
+type Manifest struct {
+	ExamplesTxt string                      `json:"examplesTxt"`
+	Settings    string                      `json:"settings"`
+	Templates   map[string]string           `json:"templates"`
+	Assets      map[string]string           `json:"assets"`
+	Examples    map[string]*ManifestExample `json:"examples"`
+
+	// Pages maps the files that aren't an example's page, such as the
+	// index and the sitemap, to the combined hash of their inputs.
+	Pages map[string]string `json:"pages"`
+
+	// Changes are the recent changes read from git history, kept for the
+	// builds that can't read it, such as those of shallow clones.
+	Changes []*Change `json:"changes"`
+}
+
+// ManifestExample is the manifest entry of a single example page.
+type ManifestExample struct {
+	Title   string            `json:"title"`
+	Sources map[string]string `json:"sources"`
+
+	// Modified is the date the sources last changed, which the sitemap and
+	// the page's structured data report. It's kept from build to build,
+	// even with -full, until they change again.
+	Modified string `json:"modified"`
+
+	// Anchors are the IDs of the rows of the example's segments, in order,
+	// which other sites can link to as <page>#<anchor>.
+	Anchors []string `json:"anchors"`
+
+	// Key is the combined hash of everything the page is rendered from:
+	// the sources, the templates, the site config and the IDs and titles
+	// of the neighbouring examples.
+	Key string `json:"key"`
+}
+
+// ReadManifest returns the manifest of a previous build in outDir, or nil
+// if there's none.
+func ReadManifest(outDir string) *Manifest {
+	data, err := os.ReadFile(filepath.Join(outDir, ManifestFile))
+	if err != nil {
+		return nil
+	}
+	var m Manifest
+	if json.Unmarshal(data, &m) != nil {
+		return nil
+	}
+	return &m
+}
+
+func (b *builder) writeManifest(m *Manifest, outDir string) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if b.failed(ManifestFile, err) {
+		return
+	}
+	path := filepath.Join(outDir, ManifestFile)
+	b.failed(path, os.WriteFile(path, append(data, '\n'), 0644))
+}
+
+func (b *builder) hashSources(id string) map[string]string {
+	sources := make(map[string]string)
+	for _, sourcePath := range b.glob(b.exampleDir(id) + "/*") {
+		if !b.isDir(sourcePath) {
+			sources[filepath.ToSlash(sourcePath)] = sha1Sum(b.readFile(sourcePath))
+		}
+	}
+	// Data files in subdirectories are shared with the playground too.
+	for _, name := range b.dataFiles(b.exampleDir(id)) {
+		if strings.Contains(name, "/") {
+			path := filepath.Join(b.exampleDir(id), name)
+			sources[filepath.ToSlash(path)] = sha1Sum(b.readFile(path))
+		}
+	}
+	if hashPath := "examples/" + id + "/" + id + ".hash"; !b.isPrimary() && b.exists(hashPath) {
+		sources[hashPath] = sha1Sum(b.readFile(hashPath))
+	}
+	if path := metaPath(id); !b.isPrimary() && b.exists(path) {
+		sources[path] = sha1Sum(b.readFile(path))
+	}
+	if b.catalog != nil {
+		sources[b.catalog.path] = b.catalog.exampleKey(id)
+	}
+	return sources
+}
+
+// inputsKey combines named hashes into one, independent of map order.
+func inputsKey(inputs map[string]string) string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s\x00%s\n", name, inputs[name])
+	}
+	return sha1Sum(sb.String())
+}
+
+func (b *builder) exampleKey(m *Manifest, example *Example, site *SiteConfig) string {
+	inputs := map[string]string{
+		"site":       fmt.Sprintf("%+v", *site),
+		"settings":   m.Settings,
+		"title":      example.Title,
+		"modified":   example.Modified,
+		"example":    m.Templates[b.templatePath("example.tmpl")],
+		"footer":     m.Templates[b.templatePath("footer.tmpl")],
+		"locales":    m.Templates[b.templatePath("locales.tmpl")],
+		"alternates": fmt.Sprintf("%+v", example.Alternates),
+		"contents":   inputsKey(m.Examples[example.ID].Sources),
+		"stdlib":     b.goCheck.stdlibHash(),
+	}
+	if example.PrevExample != nil {
+		inputs["prev"] = example.PrevExample.ID + "|" + example.PrevExample.Title
+	}
+	if example.NextExample != nil {
+		inputs["next"] = example.NextExample.ID + "|" + example.NextExample.Title
+	}
+	if s := example.Section; s != nil {
+		inputs["section"] = s.ID + "|" + s.Title
+	}
+	if s := example.NextSection(); s != nil {
+		inputs["nextSection"] = s.Examples[0].ID + "|" + s.Title
+	}
+	for i, related := range example.Related {
+		inputs[fmt.Sprintf("related%d", i)] = related.ID + "|" + related.Title
+	}
+	return inputsKey(inputs)
+}
+
+// apiIndexKey returns the key of the API index, which is rendered again
+// when any example changed.
+func (b *builder) apiIndexKey(m *Manifest, site *SiteConfig) string {
+	inputs := map[string]string{
+		"site":     fmt.Sprintf("%+v", *site),
+		"settings": m.Settings,
+		"examples": m.ExamplesTxt,
+		"api":      m.Templates[b.templatePath("api.tmpl")],
+		"footer":   m.Templates[b.templatePath("footer.tmpl")],
+		"stdlib":   b.goCheck.stdlibHash(),
+	}
+	for id, entry := range m.Examples {
+		inputs["example:"+id] = inputsKey(entry.Sources) + "|" + entry.Title
+	}
+	return inputsKey(inputs)
+}
+
+// keyStdlibPages sets the keys of the pages that show what the examples use
+// of the standard library: the examples' and the API index.
+func (b *builder) keyStdlibPages(m *Manifest, examples []*Example, site *SiteConfig) {
+	for _, example := range examples {
+		m.Examples[example.ID].Key = b.exampleKey(m, example, site)
+	}
+	m.Pages[APIIndexFile] = b.apiIndexKey(m, site)
+}
+
+// settingsHash hashes the effective settings, defaults included.
+func (b *builder) settingsHash() string {
+	data, _ := json.Marshal(b.settings)
+	return sha1Sum(string(data))
+}
+
+// upToDate reports whether the output file name was rendered from the same
+// inputs in the previous build and is still in place.
+func upToDate(outDir, name, oldKey, newKey string) bool {
+	if oldKey == "" || oldKey != newKey {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(outDir, name))
+	return err == nil
+}
+
+// Build generates the site into outDir like Render, but uses the manifest
+// of the previous build there to render only the pages whose inputs
+// changed, and removes the pages of examples no longer listed. The locales
+// of Settings.Locales are built into subdirectories of outDir, each with a
+// manifest of its own. After a clean build, it updates StdlibFile.
+func Build(cfg Config, outDir string) error {
+	b := newBuilder(cfg)
+	b.goCheck.loadStdlib(b)
+	all := b.locales()
+	lists := make([][]*Example, len(all))
+	for i, lb := range all {
+		lists[i] = lb.readExampleList()
+	}
+	for _, example := range lists[0] {
+		for _, loc := range b.settings.Locales {
+			if example.ID == loc.Path {
+				b.diags.Errorf(SettingsFile, 0, "path %q of locale %q is taken by an example", loc.Path, loc.Language)
+			}
+		}
+	}
+	linkAlternates(b.settings.BaseURL, all, lists)
+	if b.settings.BaseURL == "" {
+		b.diags.Warnf(SettingsFile, 0, "baseURL is empty, so there are no sitemaps or canonical links")
+	}
+
+	// The primary locale goes first, as it shares the code of changed
+	// examples that the others link to.
+	manifests := make([]*Manifest, len(all))
+	for i, lb := range all {
+		alts := indexAlternates(b.settings.BaseURL, lb.locale, all)
+		manifests[i] = lb.buildLocale(lists[i], alts, filepath.Join(outDir, lb.locale.Path))
+	}
+
+	// What the pages were just rendered with is what StdlibFile has once
+	// it's saved, so their keys count its new contents.
+	if b.diags.Errors() == 0 && b.saveStdlib() {
+		for i, lb := range all {
+			if manifests[i] != nil {
+				lb.keyStdlibPages(manifests[i], lists[i], lb.siteConfig())
+			}
+		}
+	}
+	for i, lb := range all {
+		if manifests[i] != nil {
+			lb.writeManifest(manifests[i], filepath.Join(outDir, lb.locale.Path))
+		}
+	}
+	return b.err()
+}
+
+// buildLocale generates the pages of the builder's locale into outDir, and
+// the assets too for the primary locale. It returns the manifest to write
+// there after a clean build, or nil.
+func (b *builder) buildLocale(examples []*Example, indexAlts []Alternate, outDir string) *Manifest {
+	if b.failed(outDir, os.MkdirAll(outDir, 0755)) {
+		return nil
+	}
+
+	prev := ReadManifest(outDir)
+	if prev == nil {
+		prev = &Manifest{}
+	}
+	old := prev
+	if b.cfg.Full {
+		old = &Manifest{}
+	}
+	m := &Manifest{
+		ExamplesTxt: sha1Sum(b.readFile(b.src("examples.txt"))),
+		Settings:    b.settingsHash(),
+		Templates:   make(map[string]string),
+		Assets:      make(map[string]string),
+		Examples:    make(map[string]*ManifestExample),
+		Pages:       make(map[string]string),
+	}
+
+	if b.isPrimary() {
+		for _, name := range b.settings.Assets {
+			m.Assets[name] = sha1Sum(b.readFile("templates/" + name))
+			if !upToDate(outDir, name, old.Assets[name], m.Assets[name]) {
+				b.copyAsset(name, outDir)
+			}
+		}
+	}
+	if b.isPrimary() {
+		b.writeGenerated(m, old, outDir, ChromaCSSFile, b.chromaCSS())
+	}
+	for _, name := range Templates {
+		path := b.templatePath(name)
+		m.Templates[path] = sha1Sum(b.readFile(path))
+	}
+
+	site := b.siteConfig()
+
+	// Only the examples whose page inputs changed are parsed and rendered.
+	// The others keep just their ID and Title, which is all the index and
+	// the neighbours' navigation links need.
+	var changed []*Example
+	for _, example := range examples {
+		m.Examples[example.ID] = &ManifestExample{
+			Title:   example.Title,
+			Sources: b.hashSources(example.ID),
+		}
+		example.Modified = modified(prev.Examples[example.ID], m.Examples[example.ID].Sources)
+		m.Examples[example.ID].Modified = example.Modified
+		oldKey := ""
+		if oldExample := old.Examples[example.ID]; oldExample != nil {
+			oldKey = oldExample.Key
+		}
+		if !upToDate(outDir, example.ID, oldKey, b.exampleKey(m, example, site)) {
+			changed = append(changed, example)
+		}
+	}
+	b.parseSelected(changed)
+
+	// Nothing is rendered from broken sources.
+	if b.diags.Errors() > 0 {
+		return nil
+	}
+
+	// Sharing may have rewritten .hash files, so sources and keys are
+	// computed again for the examples that were just parsed.
+	for _, example := range changed {
+		m.Examples[example.ID].Sources = b.hashSources(example.ID)
+		example.Modified = modified(prev.Examples[example.ID], m.Examples[example.ID].Sources)
+		m.Examples[example.ID].Modified = example.Modified
+	}
+	b.keyStdlibPages(m, examples, site)
+	for _, example := range examples {
+		entry := m.Examples[example.ID]
+		switch oldExample := old.Examples[example.ID]; {
+		case example.Segs != nil:
+			entry.Anchors = segAnchors(example.Segs)
+		case oldExample != nil && oldExample.Anchors != nil:
+			entry.Anchors = oldExample.Anchors
+		default:
+			entry.Anchors = segAnchors(b.readSegs(example))
+		}
+	}
+
+	siteKey := fmt.Sprintf("%+v", *site)
+	m.Pages["index.html"] = inputsKey(map[string]string{
+		"site":       siteKey,
+		"settings":   m.Settings,
+		"examples":   m.ExamplesTxt,
+		"index":      m.Templates[b.templatePath("index.tmpl")],
+		"footer":     m.Templates[b.templatePath("footer.tmpl")],
+		"locales":    m.Templates[b.templatePath("locales.tmpl")],
+		"alternates": fmt.Sprintf("%+v", indexAlts),
+	})
+	m.Pages["404.html"] = inputsKey(map[string]string{
+		"site":     siteKey,
+		"settings": m.Settings,
+		"404":      m.Templates[b.templatePath("404.tmpl")],
+		"footer":   m.Templates[b.templatePath("footer.tmpl")],
+	})
+
+	if !upToDate(outDir, "index.html", old.Pages["index.html"], m.Pages["index.html"]) {
+		b.renderIndex(examples, indexAlts, site, outDir)
+	}
+	b.renderExamples(changed, site, outDir)
+	if !upToDate(outDir, "404.html", old.Pages["404.html"], m.Pages["404.html"]) {
+		b.render404(site, outDir)
+	}
+
+	changes, ok := b.readHistory(examples)
+	if !ok {
+		changes = keptChanges(prev.Changes, examples)
+	}
+	m.Changes = changes
+	changesData, _ := json.Marshal(changes)
+	m.Pages[ChangelogFile] = inputsKey(map[string]string{
+		"site":      siteKey,
+		"settings":  m.Settings,
+		"changes":   sha1Sum(string(changesData)),
+		"changelog": m.Templates[b.templatePath("changelog.tmpl")],
+		"feed":      m.Templates[b.templatePath("feed.tmpl")],
+		"footer":    m.Templates[b.templatePath("footer.tmpl")],
+	})
+	oldKey := old.Pages[ChangelogFile]
+	if !upToDate(outDir, ChangelogFile, oldKey, m.Pages[ChangelogFile]) || !upToDate(outDir, FeedFile, oldKey, m.Pages[ChangelogFile]) {
+		b.renderChangelog(changes, site, outDir)
+	}
+
+	b.writeGenerated(m, old, outDir, SitemapFile, b.sitemap(examples, indexAlts))
+	if b.isPrimary() {
+		b.writeGenerated(m, old, outDir, RobotsFile, b.robots())
+	}
+
+	// The search index covers every example, so it's written again when
+	// any of them changed.
+	searchInputs := map[string]string{"examples": m.ExamplesTxt}
+	for id, entry := range m.Examples {
+		searchInputs[id] = inputsKey(entry.Sources) + strings.Join(entry.Anchors, " ")
+	}
+	m.Pages[SearchFile] = inputsKey(searchInputs)
+	if !upToDate(outDir, SearchFile, old.Pages[SearchFile], m.Pages[SearchFile]) {
+		b.writeSearchIndex(examples, outDir)
+	}
+
+	// So is the API index, whose key keyStdlibPages set.
+	if !upToDate(outDir, APIIndexFile, old.Pages[APIIndexFile], m.Pages[APIIndexFile]) {
+		b.renderAPIIndex(examples, site, outDir)
+	}
+
+	// Remove the pages of examples that were dropped from examples.txt,
+	// which the previous manifest knows of even in a full build.
+	for id := range prev.Examples {
+		if m.Examples[id] == nil {
+			b.logf("Removing %s", filepath.Join(b.locale.Path, id))
+			os.Remove(filepath.Join(outDir, id))
+		}
+	}
+	for name := range prev.Assets {
+		if m.Assets[name] == "" {
+			os.Remove(filepath.Join(outDir, name))
+		}
+	}
+
+	// The manifest is only written after a clean build, so that pages that
+	// failed to render are retried next time.
+	if b.diags.Errors() > 0 {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,105 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyTemplates copies the repository's templates into root.
+func copyTemplates(t *testing.T, root string) {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join("..", "templates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join("..", "templates", entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, root, "templates/"+entry.Name(), string(data))
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	root, out := t.TempDir(), t.TempDir()
+	copyTemplates(t, root)
+	writeFiles(t, root,
+		"examples.txt", "one|One\ntwo|Two\nthree|Three\n",
+		"examples/one/one.go", "package main\n\nfunc main() {}\n",
+		"examples/two/two.go", "package main\n\nfunc main() {}\n",
+		"examples/three/three.go", "package main\n\nfunc main() {}\n")
+	build := func(full bool) {
+		t.Helper()
+		if err := Build(Config{Root: root, Settings: DefaultSettings(), Full: full}, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// stale marks the pages, which keep the mark unless they're rendered
+	// again.
+	const mark = "not rendered again"
+	stale := func(ids ...string) {
+		t.Helper()
+		for _, id := range ids {
+			writeFiles(t, out, id, mark)
+		}
+	}
+	rendered := func(id string) bool {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data) != mark
+	}
+	check := func(step string, want map[string]bool) {
+		t.Helper()
+		for id, w := range want {
+			if got := rendered(id); got != w {
+				t.Errorf("%s: %s rendered again = %v, want %v", step, id, got, w)
+			}
+		}
+	}
+
+	build(false)
+	if ReadManifest(out) == nil {
+		t.Fatal("no manifest after the first build")
+	}
+	stale("one", "two", "three")
+	build(false)
+	check("no changes", map[string]bool{"one": false, "two": false, "three": false})
+
+	// The title of an example is in the navigation of its neighbours.
+	writeFiles(t, root, "examples.txt", "one|One\ntwo|Second\nthree|Three\n")
+	build(false)
+	check("new title", map[string]bool{"one": true, "two": true, "three": true})
+
+	stale("one", "two", "three")
+	writeFiles(t, root, "examples/three/three.go", "package main\n\n// Changed.\nfunc main() {}\n")
+	build(false)
+	check("changed code", map[string]bool{"one": false, "two": false, "three": true})
+	if page, _ := os.ReadFile(filepath.Join(out, "three")); !strings.Contains(string(page), "Changed.") {
+		t.Errorf("changed code: the page of three doesn't have the new docs")
+	}
+
+	stale("one", "two", "three")
+	writeFiles(t, root, "examples.txt", "one|One\ntwo|Second\n")
+	build(false)
+	check("removed example", map[string]bool{"one": false, "two": true})
+	if _, err := os.Stat(filepath.Join(out, "three")); !os.IsNotExist(err) {
+		t.Errorf("removed example: the page of three is still there")
+	}
+
+	stale("one")
+	writeFiles(t, root, "examples.txt", "one|One\n")
+	build(true)
+	check("full build", map[string]bool{"one": true})
+	if _, err := os.Stat(filepath.Join(out, "two")); !os.IsNotExist(err) {
+		t.Errorf("full build: the page of the removed example two is still there")
+	}
+	if m := ReadManifest(out); m == nil || len(m.Examples) != 1 || m.Examples["one"] == nil {
+		t.Errorf("full build: manifest lists %v, want only one", m)
+	}
+}
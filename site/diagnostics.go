@@ -0,0 +1,106 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Diagnostic is a problem found while building the site, located at a file
+// and, where it's known, a line.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	pos := d.File
+	if d.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", d.File, d.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+}
+
+// Diagnostics collects every problem found during a build, so that one
+// broken example doesn't hide the others. It's safe for concurrent use. The
+// errors returned by the functions of this package are always a
+// *Diagnostics holding at least one error.
+type Diagnostics struct {
+	mu   sync.Mutex
+	list []Diagnostic
+}
+
+func (d *Diagnostics) add(severity, file string, line int, format string, args ...any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.list = append(d.list, Diagnostic{
+		File:     file,
+		Line:     line,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Errorf records an error at file and line; line 0 means the whole file.
+func (d *Diagnostics) Errorf(file string, line int, format string, args ...any) {
+	d.add("error", file, line, format, args...)
+}
+
+// Warnf records a warning at file and line; line 0 means the whole file.
+func (d *Diagnostics) Warnf(file string, line int, format string, args ...any) {
+	d.add("warning", file, line, format, args...)
+}
+
+// List returns the diagnostics sorted by position.
+func (d *Diagnostics) List() []Diagnostic {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	list := append([]Diagnostic{}, d.list...)
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].File != list[j].File {
+			return list[i].File < list[j].File
+		}
+		return list[i].Line < list[j].Line
+	})
+	return list
+}
+
+// Errors returns the number of error diagnostics collected so far.
+func (d *Diagnostics) Errors() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, diag := range d.list {
+		if diag.Severity == "error" {
+			n++
+		}
+	}
+	return n
+}
+
+// Print writes all diagnostics sorted by position, one per line like a
+// compiler does, or as a JSON array.
+func (d *Diagnostics) Print(w io.Writer, asJSON bool) {
+	list := d.List()
+	if asJSON {
+		data, _ := json.MarshalIndent(list, "", "  ")
+		fmt.Fprintln(w, string(data))
+		return
+	}
+	for _, diag := range list {
+		fmt.Fprintln(w, diag)
+	}
+}
+
+func (d *Diagnostics) Error() string {
+	var lines []string
+	for _, diag := range d.List() {
+		lines = append(lines, diag.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,384 @@
+package site
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"go/version"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GoRequirement is the oldest Go release an example's code builds with,
+// and what in the code needs it.
+type GoRequirement struct {
+	// Version is the release without the "go" prefix, e.g. "1.23", or
+	// "1.0" for code that any Go 1 builds.
+	Version string
+
+	// Reason names the newest language feature or standard library API
+	// the code uses, e.g. "range over func" or "sync.WaitGroup.Go",
+	// empty for Go 1.0.
+	Reason string
+}
+
+// goBadgeFloor is the oldest release that pages show as a badge. Every Go
+// that readers are likely to have builds code that needs an older one, so
+// saying so is noise.
+const goBadgeFloor = "go1.18"
+
+// Shown reports whether pages show the requirement, see goBadgeFloor.
+func (r GoRequirement) Shown() bool {
+	return r.Version != "" && version.Compare("go"+r.Version, goBadgeFloor) >= 0
+}
+
+// goChecker type checks the code of examples, to work out the Go releases
+// they need and what they use from the standard library. It reads the
+// standard library's sources and its api/ files from GOROOT the first time
+// it's used, and is shared by the builders of all locales. What pages show
+// of the api/ files and the docs comes from StdlibFile where it has it.
+type goChecker struct {
+	once sync.Once
+	err  error
+	api  *apiVersions
+
+	// mu guards the importer, which isn't safe for concurrent use, what
+	// was found so far by the hash of the code, the docs read so far by
+	// import path, and the data of StdlibFile, as it was read in saved,
+	// with what was looked up in GOROOT since and what was used of it.
+	mu           sync.Mutex
+	fset         *token.FileSet
+	imp          types.Importer
+	found        map[string]*codeInfo
+	docs         map[string]map[string]string
+	stdlib, used *stdlibData
+	saved        string
+}
+
+// codeInfo is what type checking the Go files of an example found.
+type codeInfo struct {
+	req GoRequirement
+
+	// refs are the uses of standard library names by file name, and
+	// imports the import paths of the standard library packages the files
+	// use, by package name.
+	refs    map[string][]stdRef
+	imports map[string]string
+
+	// others are the rest of what the API index lists by file name: the
+	// imports of standard library packages, with an empty key, the uses
+	// of builtins, in the package "builtin", and the keywords, with an
+	// empty path. packages are the standard library packages the files
+	// import, in order.
+	others   map[string][]stdRef
+	packages []string
+}
+
+// apiVersions are the releases that added the exported names of the
+// standard library: names maps "path.Name" and "path.Type.Member" to a
+// version like "go1.21", and pkgs maps an import path to the release of
+// its oldest names.
+type apiVersions struct {
+	names, pkgs map[string]string
+}
+
+// analyzeGo type checks the Go files of an example, or returns nil if
+// there is no GOROOT to do it with, which the first call warns about.
+func (b *builder) analyzeGo(dir string, files []playgroundFile) *codeInfo {
+	c := b.goCheck
+	c.once.Do(func() {
+		c.fset = token.NewFileSet()
+		c.imp = importer.ForCompiler(c.fset, "source", nil)
+		c.found = make(map[string]*codeInfo)
+		c.docs = make(map[string]map[string]string)
+		c.loadStdlib(b)
+		c.api, c.err = readAPIVersions(filepath.Join(build.Default.GOROOT, "api"))
+		if c.err != nil {
+			b.diags.Warnf(dir, 0, "can't type check the examples with the standard library: %v", c.err)
+		}
+	})
+	if c.err != nil {
+		return nil
+	}
+
+	key := sha1Sum(formatTxtar(files))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info, ok := c.found[key]; ok {
+		return info
+	}
+	info := c.check(files)
+	c.found[key] = info
+	return info
+}
+
+// checkGoVersion sets the Go release an example needs, and reports an
+// error if its meta.json says otherwise.
+func (b *builder) checkGoVersion(example *Example, info *codeInfo) {
+	req := info.req
+	example.MinGo = req
+	declared := example.Meta.GoVersion
+	if declared == "" || !b.isPrimary() || version.Compare("go"+declared, "go"+req.Version) == 0 {
+		return
+	}
+	because := ""
+	if req.Reason != "" {
+		because = " for " + req.Reason
+	}
+	b.diags.Errorf(metaPath(example.ID), 0, "goVersion is %q, but the code needs Go %s%s", declared, req.Version, because)
+}
+
+// check type checks the files, a package per package clause, for the
+// newest release among the language features and the standard library
+// names they use, and for the uses of those names. Errors are ignored: the
+// build of the example reports them, and what could be checked still
+// counts.
+func (c *goChecker) check(files []playgroundFile) *codeInfo {
+	byPkg := make(map[string][]*ast.File)
+	srcs := make(map[*ast.File]string)
+	var order []string
+	for _, f := range files {
+		file, err := parser.ParseFile(c.fset, f.name, f.data, parser.SkipObjectResolution)
+		if file == nil || err != nil && file.Name == nil {
+			continue
+		}
+		name := file.Name.Name
+		if byPkg[name] == nil {
+			order = append(order, name)
+		}
+		byPkg[name] = append(byPkg[name], file)
+		srcs[file] = f.data
+	}
+
+	need := &needs{version: "go1"}
+	code := &codeInfo{
+		refs:    make(map[string][]stdRef),
+		imports: make(map[string]string),
+		others:  make(map[string][]stdRef),
+	}
+	for _, name := range order {
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Instances:  make(map[*ast.Ident]types.Instance),
+		}
+		conf := types.Config{Importer: c.imp, Error: func(error) {}}
+		pkg, _ := conf.Check(name, c.fset, byPkg[name], info)
+		for _, file := range byPkg[name] {
+			c.inspect(file, pkg, info, need)
+			c.collectRefs(file, srcs[file], info, code)
+			c.collectKeywords(file, srcs[file], code)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, refs := range code.others {
+		for _, ref := range refs {
+			if ref.key == "" && ref.path != "" && !seen[ref.path] {
+				seen[ref.path] = true
+				code.packages = append(code.packages, ref.path)
+			}
+		}
+	}
+	sort.Strings(code.packages)
+	code.req = GoRequirement{Version: displayVersion(need.version), Reason: need.reason}
+	return code
+}
+
+// needs collects the newest release that something in the code needs.
+type needs struct {
+	version, reason string
+}
+
+func (n *needs) add(v, reason string) {
+	if v != "" && version.Compare(v, n.version) > 0 {
+		n.version, n.reason = v, reason
+	}
+}
+
+// inspect adds what a file needs to need.
+func (c *goChecker) inspect(file *ast.File, pkg *types.Package, info *types.Info, need *needs) {
+	for _, spec := range file.Imports {
+		path := strings.Trim(spec.Path.Value, `"`)
+		need.add(c.pkgVersion(path), "package "+path)
+	}
+	if len(info.Instances) > 0 {
+		need.add("go1.18", "generics")
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncType:
+			if n.TypeParams != nil {
+				need.add("go1.18", "generics")
+			}
+		case *ast.TypeSpec:
+			if n.TypeParams != nil {
+				need.add("go1.18", "generics")
+			}
+		case *ast.RangeStmt:
+			if t := info.TypeOf(n.X); t != nil {
+				switch u := t.Underlying().(type) {
+				case *types.Basic:
+					if u.Info()&types.IsInteger != 0 {
+						need.add("go1.22", "range over int")
+					}
+				case *types.Signature:
+					need.add("go1.23", "range over func")
+				}
+			}
+		case *ast.BasicLit:
+			if n.Kind == token.INT || n.Kind == token.FLOAT || n.Kind == token.IMAG {
+				lit := strings.ToLower(n.Value)
+				if strings.Contains(lit, "_") || strings.HasPrefix(lit, "0b") || strings.HasPrefix(lit, "0o") {
+					need.add("go1.13", "number literal "+n.Value)
+				}
+			}
+		case *ast.Ident:
+			switch obj := info.Uses[n].(type) {
+			case *types.Builtin:
+				switch obj.Name() {
+				case "min", "max", "clear":
+					need.add("go1.21", "builtin "+obj.Name())
+				}
+			case *types.TypeName:
+				if obj.Parent() == types.Universe {
+					switch obj.Name() {
+					case "any", "comparable":
+						need.add("go1.18", obj.Name())
+					}
+				}
+			}
+		case *ast.SelectorExpr:
+			if sel := info.Selections[n]; sel != nil {
+				if named := namedType(sel.Recv()); named != nil && named.Obj().Pkg() != nil && named.Obj().Pkg() != pkg {
+					typePkg := named.Obj().Pkg()
+					name := named.Obj().Name() + "." + n.Sel.Name
+					need.add(c.nameVersion(typePkg.Path()+"."+name), typePkg.Name()+"."+name)
+				}
+			} else if obj := info.Uses[n.Sel]; obj != nil && obj.Pkg() != nil && obj.Pkg() != pkg && obj.Parent() == obj.Pkg().Scope() {
+				need.add(c.nameVersion(obj.Pkg().Path()+"."+obj.Name()), obj.Pkg().Name()+"."+obj.Name())
+			}
+		}
+		return true
+	})
+}
+
+// namedType returns the named type of t, or of what it points to.
+func namedType(t types.Type) *types.Named {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, _ := types.Unalias(t).(*types.Named)
+	return named
+}
+
+// displayVersion turns a version like "go1.21" into "1.21", and "go1" into
+// "1.0".
+func displayVersion(v string) string {
+	v = strings.TrimPrefix(version.Lang(v), "go")
+	if !strings.Contains(v, ".") {
+		v += ".0"
+	}
+	return v
+}
+
+// readAPIVersions reads the go1*.txt files of a GOROOT's api/ directory,
+// whose lines list the names each release added, like
+//
+//	pkg sync, method (*WaitGroup) Go(func()) #63796
+func readAPIVersions(dir string) (*apiVersions, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "go1*.txt"))
+	if err == nil && len(paths) == 0 {
+		err = fmt.Errorf("no go1*.txt files in %s", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	api := &apiVersions{names: make(map[string]string), pkgs: make(map[string]string)}
+	add := func(m map[string]string, key, v string) {
+		if old, ok := m[key]; !ok || version.Compare(v, old) < 0 {
+			m[key] = v
+		}
+	}
+	for _, path := range paths {
+		v := strings.TrimSuffix(filepath.Base(path), ".txt")
+		if !version.IsValid(v) {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			pkg, name, ok := parseAPILine(s.Text())
+			if !ok {
+				continue
+			}
+			add(api.pkgs, pkg, v)
+			add(api.names, pkg+"."+name, v)
+		}
+		err = s.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return api, nil
+}
+
+// parseAPILine returns the import path and the name, "Name" or
+// "Type.Member", that a line of an api/ file adds. Deprecations add
+// nothing.
+func parseAPILine(line string) (pkg, name string, ok bool) {
+	rest, ok := strings.CutPrefix(line, "pkg ")
+	if !ok || strings.Contains(line, "//deprecated") {
+		return "", "", false
+	}
+	pkg, rest, ok = strings.Cut(rest, ", ")
+	if !ok {
+		return "", "", false
+	}
+	// Names of one platform only, like "pkg syscall (linux-386)".
+	pkg, _, _ = strings.Cut(pkg, " ")
+
+	kind, rest, _ := strings.Cut(rest, " ")
+	switch kind {
+	case "func", "const", "var":
+		name = leadingIdent(rest)
+	case "method":
+		recv, method, ok := strings.Cut(strings.TrimPrefix(rest, "("), ") ")
+		if !ok {
+			return "", "", false
+		}
+		name = leadingIdent(strings.TrimPrefix(recv, "*")) + "." + leadingIdent(method)
+	case "type":
+		name = leadingIdent(rest)
+		// "type T struct, F int" adds a field and "type T interface, M()"
+		// a method.
+		if _, member, ok := strings.Cut(rest, ", "); ok {
+			name += "." + leadingIdent(member)
+		}
+	default:
+		return "", "", false
+	}
+	return pkg, name, name != "" && !strings.HasPrefix(name, ".") && !strings.HasSuffix(name, ".")
+}
+
+// leadingIdent returns the identifier s starts with.
+func leadingIdent(s string) string {
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return s[:i]
+		}
+	}
+	return s
+}
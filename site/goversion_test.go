@@ -0,0 +1,76 @@
+package site
+
+import (
+	"go/importer"
+	"go/token"
+	"testing"
+)
+
+func TestParseAPILine(t *testing.T) {
+	tests := []struct {
+		line, pkg, name string
+		ok              bool
+	}{
+		{"pkg sync, method (*WaitGroup) Go(func()) #63796", "sync", "WaitGroup.Go", true},
+		{"pkg iter, type Seq[$0 interface{}] func(func($0) bool) #61897", "iter", "Seq", true},
+		{"pkg testing, method (*B) Loop() bool #61515", "testing", "B.Loop", true},
+		{"pkg net/http, type Request struct, Pattern string #61410", "net/http", "Request.Pattern", true},
+		{"pkg io, type ReadSeekCloser interface, Close() error", "io", "ReadSeekCloser.Close", true},
+		{"pkg syscall (linux-386), const AF_ALG = 38", "syscall", "AF_ALG", true},
+		{"pkg slices, func Max[$0 interface{ ~[]$1 }, $1 cmp.Ordered]($0) $1 #60091", "slices", "Max", true},
+		{"pkg crypto/elliptic, func Marshal //deprecated #52221", "", "", false},
+	}
+	for _, tt := range tests {
+		pkg, name, ok := parseAPILine(tt.line)
+		if pkg != tt.pkg || name != tt.name || ok != tt.ok {
+			t.Errorf("parseAPILine(%q) = %q, %q, %t, want %q, %q, %t", tt.line, pkg, name, ok, tt.pkg, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestGoRequirement(t *testing.T) {
+	fset := token.NewFileSet()
+	c := &goChecker{
+		fset:   fset,
+		imp:    importer.ForCompiler(fset, "source", nil),
+		api:    &apiVersions{names: map[string]string{}, pkgs: map[string]string{}},
+		docs:   make(map[string]map[string]string),
+		stdlib: newStdlibData(),
+		used:   newStdlibData(),
+	}
+	tests := []struct {
+		code, version, reason string
+	}{
+		{"func main() {}", "1.0", ""},
+		{"func main() { x := 0b101; _ = x }", "1.13", "number literal 0b101"},
+		{"func f[T any](t T) T { return t }", "1.18", "generics"},
+		{"func main() { _ = min(1, 2) }", "1.21", "builtin min"},
+		{"func main() { for range 3 {} }", "1.22", "range over int"},
+		{"func seq(yield func(int) bool) {}\nfunc main() { for range seq {} }", "1.23", "range over func"},
+	}
+	for _, tt := range tests {
+		info := c.check([]playgroundFile{{"main.go", "package main\n\n" + tt.code + "\n"}})
+		req := info.req
+		if req.Version != tt.version || req.Reason != tt.reason {
+			t.Errorf("%s: got Go %s for %q, want Go %s for %q", tt.code, req.Version, req.Reason, tt.version, tt.reason)
+		}
+	}
+}
+
+func TestGoRequirementShown(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"", false},
+		{"1.0", false},
+		{"1.16", false},
+		{"1.18", true},
+		{"1.23", true},
+	}
+	for _, tt := range tests {
+		if got := (GoRequirement{Version: tt.version}).Shown(); got != tt.want {
+			t.Errorf("Go %q shown = %t, want %t", tt.version, got, tt.want)
+		}
+	}
+}
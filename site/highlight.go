@@ -0,0 +1,92 @@
+package site
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// ChromaCSSFile is the stylesheet of highlighted code, generated from the
+// chroma styles in site.json and written next to the assets.
+const ChromaCSSFile = "chroma.css"
+
+// The site's own chroma styles, registered so that site.json can name
+// them like the built-in ones.
+func init() {
+	styles.Register(chroma.MustNewStyle("gobyexample", chroma.StyleEntries{
+		chroma.Background:    "#252519 bg:#f0f0f0",
+		chroma.Keyword:       "#954121",
+		chroma.KeywordType:   "#b00040",
+		chroma.NameBuiltin:   "#954121",
+		chroma.LiteralNumber: "#666666",
+		chroma.LiteralString: "#219161",
+		chroma.Comment:       "#808080",
+		chroma.GenericPrompt: "#000080",
+		chroma.GenericOutput: "#808080",
+	}))
+	styles.Register(chroma.MustNewStyle("gobyexample-dark", chroma.StyleEntries{
+		chroma.Background:    "#dadada bg:#282828",
+		chroma.Keyword:       "#af5a54",
+		chroma.KeywordType:   "#b64343",
+		chroma.NameBuiltin:   "#af5a54",
+		chroma.LiteralNumber: "#688ec8",
+		chroma.LiteralString: "#718e72",
+		chroma.Comment:       "#868686",
+		chroma.GenericPrompt: "#8a6ab1",
+		chroma.GenericOutput: "#868686",
+	}))
+}
+
+// styleRules returns the CSS rules of a chroma style: its background for
+// the code column, and a rule per token class that the style sets anything
+// else for, in the order of the token types. The HTML formatter gives every
+// token the class of its type whatever the style, so the rules of any
+// style apply to the same pages.
+func styleRules(style *chroma.Style, indent string) string {
+	bg := style.Get(chroma.Background)
+	var types []chroma.TokenType
+	for tt, class := range chroma.StandardTypes {
+		if tt > 0 && class != "" {
+			types = append(types, tt)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%std.code { %s } /* %s */\n", indent, html.StyleEntryToCSS(bg), chroma.Background)
+	for _, tt := range types {
+		css := html.StyleEntryToCSS(style.Get(tt).Sub(bg))
+		if css == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s.chroma .%s { %s } /* %s */\n", indent, chroma.StandardTypes[tt], css, tt)
+	}
+	return sb.String()
+}
+
+// chromaCSS returns the stylesheet of highlighted code. With a dark style
+// as well, each style's rules only apply in its color scheme, so that
+// neither has to undo what the other sets.
+func (b *builder) chromaCSS() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "/* Generated by tools/generate from the chroma style %q", b.settings.Style)
+	if b.settings.DarkStyle != "" {
+		fmt.Fprintf(&sb, " and, in dark mode, %q", b.settings.DarkStyle)
+	}
+	sb.WriteString("; see site.json. */\n")
+	light := chromaStyle(b.settings.Style)
+	if b.settings.DarkStyle == "" {
+		sb.WriteString(styleRules(light, ""))
+		return sb.String()
+	}
+	sb.WriteString("@media not all and (prefers-color-scheme: dark) {\n")
+	sb.WriteString(styleRules(light, "  "))
+	sb.WriteString("}\n@media (prefers-color-scheme: dark) {\n")
+	sb.WriteString(styleRules(chromaStyle(b.settings.DarkStyle), "  "))
+	sb.WriteString("}\n")
+	return sb.String()
+}
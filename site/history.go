@@ -0,0 +1,243 @@
+package site
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChangelogFile and FeedFile are the changelog page and the Atom feed of
+// recently added and changed examples, written next to the index page.
+const (
+	ChangelogFile = "changelog"
+	FeedFile      = "feed.atom"
+)
+
+// historyLen is the number of changes the changelog and the feed list.
+const historyLen = 50
+
+// Change is an example added or changed by a commit. The commit's subject
+// is left out, as it's written for the repository and not for readers.
+type Change struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Commit string `json:"commit"`
+
+	// Time is the commit's author date in RFC 3339 format.
+	Time string `json:"time"`
+
+	// Added is set for the commit that listed the example in examples.txt,
+	// or that first touched its sources if it never was.
+	Added bool `json:"added,omitempty"`
+
+	// position is the example's place in examples.txt, which orders the
+	// changes of the same commit.
+	position int
+}
+
+// Date returns the day of the change.
+func (c *Change) Date() string {
+	day, _, _ := strings.Cut(c.Time, "T")
+	return day
+}
+
+// UUID returns a name-based UUID of the change, which the feed uses as the
+// ID of its entry.
+func (c *Change) UUID() string {
+	return nameUUID(c.Commit + ":" + c.ID)
+}
+
+// nameUUID returns a version 5 style UUID derived from name.
+func nameUUID(name string) string {
+	sum := sha1.Sum([]byte(name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// gitCommit is a commit from git log, with the lines git printed after it.
+type gitCommit struct {
+	hash  string
+	time  time.Time
+	lines []string
+}
+
+// git runs git in the root with args and returns its output.
+func (b *builder) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = b.path(".")
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		err = errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
+
+// gitLog runs git log in the root with args, newest commit first, with
+// paths relative to the root.
+func (b *builder) gitLog(args ...string) ([]gitCommit, error) {
+	out, err := b.git(append([]string{"log", "--no-renames", "--relative", "--format=%x00%H%x00%aI"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []gitCommit
+	parts := strings.Split(out, "\x00")
+	for i := 1; i+1 < len(parts); i += 2 {
+		date, rest, _ := strings.Cut(parts[i+1], "\n")
+		t, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			return nil, err
+		}
+		var lines []string
+		for _, line := range strings.Split(rest, "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		commits = append(commits, gitCommit{hash: parts[i], time: t, lines: lines})
+	}
+	return commits, nil
+}
+
+// readHistory reads the changes to the listed examples from git history,
+// newest first: the commits that listed them in examples.txt and the later
+// ones that changed their sources. Commits that only rewrite .hash or
+// meta.json files, or only change whitespace and blank lines as gofmt
+// does, aren't changes. It reports false, after a warning, when there is
+// no full history to read, and without a warning when Config.History isn't
+// set.
+func (b *builder) readHistory(examples []*Example) ([]*Change, bool) {
+	if !b.cfg.History {
+		return nil, false
+	}
+	listPath := filepath.ToSlash(b.src("examples.txt"))
+	dir := strings.TrimSuffix(filepath.ToSlash(b.exampleDir("")), "/")
+	shallow, err := b.git("rev-parse", "--is-shallow-repository")
+	if err == nil && strings.TrimSpace(shallow) == "true" {
+		err = errors.New("the clone is shallow")
+	}
+	var listings, edits []gitCommit
+	if err == nil {
+		listings, err = b.gitLog("-p", "-U0", "--", listPath)
+	}
+	if err == nil {
+		edits, err = b.gitLog("-w", "--ignore-blank-lines", "--numstat", "--", dir, ":(exclude)*.hash", ":(exclude)*/"+MetaFile)
+	}
+	if err != nil {
+		b.diags.Warnf(listPath, 0, "no git history for the changelog, keeping the previous one: %v", err)
+		return nil, false
+	}
+
+	listed := make(map[string]*Example)
+	for _, example := range examples {
+		listed[example.ID] = example
+	}
+
+	// The oldest commit that lists an example adds it; git log lists it
+	// last.
+	added := make(map[string]gitCommit)
+	for _, commit := range listings {
+		for _, line := range commit.lines {
+			entry, ok := strings.CutPrefix(line, "+")
+			if !ok || strings.HasPrefix(entry, "++") {
+				continue
+			}
+			id, _, _ := strings.Cut(entry, "|")
+			if id = strings.TrimSpace(id); listed[id] != nil {
+				added[id] = commit
+			}
+		}
+	}
+
+	// Commits that change only whitespace and blank lines have no --numstat
+	// lines when those are ignored.
+	touched := make(map[string][]gitCommit)
+	for _, commit := range edits {
+		seen := make(map[string]bool)
+		for _, line := range commit.lines {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) < 3 {
+				continue
+			}
+			rest, ok := strings.CutPrefix(fields[2], dir+"/")
+			if !ok {
+				continue
+			}
+			id, _, _ := strings.Cut(rest, "/")
+			if listed[id] != nil && !seen[id] {
+				seen[id] = true
+				touched[id] = append(touched[id], commit)
+			}
+		}
+	}
+
+	var changes []*Change
+	change := func(id string, commit gitCommit, isAdded bool) {
+		changes = append(changes, &Change{
+			ID:       id,
+			Title:    listed[id].Title,
+			Commit:   commit.hash,
+			Time:     commit.time.Format(time.RFC3339),
+			Added:    isAdded,
+			position: listed[id].position,
+		})
+	}
+	for _, example := range examples {
+		commits := touched[example.ID]
+		first, ok := added[example.ID]
+		if !ok {
+			if len(commits) == 0 {
+				continue
+			}
+			first = commits[len(commits)-1]
+		}
+		change(example.ID, first, true)
+		for _, commit := range commits {
+			if commit.hash != first.hash && commit.time.After(first.time) {
+				change(example.ID, commit, false)
+			}
+		}
+	}
+	sortChanges(changes)
+	if len(changes) > historyLen {
+		changes = changes[:historyLen]
+	}
+	return changes, true
+}
+
+// sortChanges orders changes newest first, and those of the same commit as
+// their examples are listed.
+func sortChanges(changes []*Change) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, changes[i].Time)
+		tj, _ := time.Parse(time.RFC3339, changes[j].Time)
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return changes[i].position < changes[j].position
+	})
+}
+
+// keptChanges returns the changes recorded by a previous build for the
+// examples still listed, with their current titles.
+func keptChanges(old []*Change, examples []*Example) []*Change {
+	listed := make(map[string]*Example)
+	for _, example := range examples {
+		listed[example.ID] = example
+	}
+	var changes []*Change
+	for _, c := range old {
+		if example := listed[c.ID]; example != nil {
+			kept := *c
+			kept.Title = example.Title
+			kept.position = example.position
+			changes = append(changes, &kept)
+		}
+	}
+	return changes
+}
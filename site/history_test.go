@@ -0,0 +1,149 @@
+package site
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitRepo is a throwaway git repository for history tests.
+type gitRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newGitRepo(t *testing.T) *gitRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	r := &gitRepo{t: t, dir: t.TempDir()}
+	r.git("", "init", "-q")
+	return r
+}
+
+// git runs git in the repository, with date as the author and committer
+// date if it isn't empty.
+func (r *gitRepo) git(date string, args ...string) {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_SYSTEM=/dev/null")
+	if date != "" {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// commit writes files, given as pairs of paths and contents, and commits
+// them with all other changes.
+func (r *gitRepo) commit(date, subject string, files ...string) {
+	r.t.Helper()
+	for i := 0; i+1 < len(files); i += 2 {
+		path := filepath.Join(r.dir, files[i])
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(files[i+1]), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.git(date, "add", "-A")
+	r.git(date, "commit", "-q", "-m", subject)
+}
+
+func TestReadHistory(t *testing.T) {
+	r := newGitRepo(t)
+	r.commit("2024-01-01T10:00:00Z", "Add Hello",
+		"examples.txt", "hello|Hello\n",
+		"examples/hello/hello.go", "package main\n\nfunc main() {\n}\n")
+	r.commit("2024-02-01T10:00:00Z", "Start Values",
+		"examples/values/values.go", "package main\n")
+	r.commit("2024-03-01T10:00:00Z", "List Values",
+		"examples.txt", "hello|Hello\nvalues|Values\n")
+	r.commit("2024-04-01T10:00:00Z", "Reshare Hello",
+		"examples/hello/hello.hash", "abc\nxyz\n")
+	r.commit("2024-05-01T10:00:00Z", "Reformat Hello",
+		"examples/hello/hello.go", "package main\n\nfunc main()  {\n\n}\n")
+	r.commit("2024-06-01T10:00:00Z", "Print from Hello",
+		"examples/hello/hello.go", "package main\n\nfunc main() {\n\tprintln()\n}\n")
+	r.commit("2024-06-15T10:00:00Z", "Describe Hello",
+		"examples/hello/meta.json", "{\"tags\": [\"basics\"]}\n")
+	r.commit("2024-07-01T10:00:00Z", "Start an unlisted example",
+		"examples/draft/draft.go", "package main\n")
+
+	b := newBuilder(Config{Root: r.dir, History: true, Settings: DefaultSettings()})
+	changes, ok := b.readHistory(b.readExampleList())
+	if !ok {
+		t.Fatalf("readHistory failed: %v", b.diags.List())
+	}
+
+	want := []struct {
+		id, date string
+		added    bool
+	}{
+		{"hello", "2024-06-01", false},
+		{"values", "2024-03-01", true},
+		{"hello", "2024-01-01", true},
+	}
+	if len(changes) != len(want) {
+		for _, c := range changes {
+			t.Logf("%+v", *c)
+		}
+		t.Fatalf("got %d changes, want %d", len(changes), len(want))
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.ID != w.id || c.Date() != w.date || c.Added != w.added {
+			t.Errorf("change %d = %s %s added=%t, want %s %s added=%t",
+				i, c.ID, c.Date(), c.Added, w.id, w.date, w.added)
+		}
+	}
+	if changes[0].Title != "Hello" {
+		t.Errorf("title = %q, want %q", changes[0].Title, "Hello")
+	}
+	if changes[0].UUID() == changes[2].UUID() {
+		t.Errorf("changes of different commits have the same UUID %s", changes[0].UUID())
+	}
+}
+
+func TestReadHistoryWithoutRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "examples.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Keep git from finding a repository above the temporary directory.
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	b := newBuilder(Config{Root: dir, History: true, Settings: DefaultSettings()})
+	if _, ok := b.readHistory(nil); ok {
+		t.Fatal("readHistory succeeded outside a repository")
+	}
+	if list := b.diags.List(); len(list) != 1 || list[0].Severity != "warning" {
+		t.Errorf("diagnostics = %v, want one warning", list)
+	}
+}
+
+func TestKeptChanges(t *testing.T) {
+	old := []*Change{
+		{ID: "hello", Title: "Hello", Time: "2024-06-01T10:00:00Z"},
+		{ID: "gone", Title: "Gone", Time: "2024-05-01T10:00:00Z", Added: true},
+	}
+	examples := []*Example{{ID: "hello", Title: "Hello, World"}}
+	changes := keptChanges(old, examples)
+	if len(changes) != 1 || changes[0].ID != "hello" || changes[0].Title != "Hello, World" {
+		t.Errorf("keptChanges = %+v, want just hello with its new title", changes)
+	}
+	if old[0].Title != "Hello" {
+		t.Errorf("keptChanges changed the old changes")
+	}
+}
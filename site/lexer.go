@@ -0,0 +1,42 @@
+package site
+
+import "github.com/alecthomas/chroma/v2"
+
+// SimpleShellOutputLexer highlights the .sh transcripts of the examples:
+// lines starting with $ or > are prompts and everything else is output.
+var SimpleShellOutputLexer = chroma.MustNewLexer(
+	&chroma.Config{
+		Name:      "Shell Output",
+		Aliases:   []string{"console"},
+		Filenames: []string{"*.sh"},
+		MimeTypes: []string{},
+	},
+	func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				// $ or > triggers the start of prompt formatting
+				{Pattern: `^\$`, Type: chroma.GenericPrompt, Mutator: chroma.Push("prompt")},
+				{Pattern: `^>`, Type: chroma.GenericPrompt, Mutator: chroma.Push("prompt")},
+
+				// empty lines are just text
+				{Pattern: `^$\n`, Type: chroma.Text, Mutator: nil},
+
+				// otherwise its all output
+				{Pattern: `[^\n]+$\n?`, Type: chroma.GenericOutput, Mutator: nil},
+			},
+			"prompt": {
+				// when we find newline, do output formatting rules
+				{Pattern: `\n`, Type: chroma.Text, Mutator: chroma.Push("output")},
+				// otherwise its all text
+				{Pattern: `[^\n]+$`, Type: chroma.Text, Mutator: nil},
+			},
+			"output": {
+				// sometimes there isn't output so we go right back to prompt
+				{Pattern: `^\$`, Type: chroma.GenericPrompt, Mutator: chroma.Pop(1)},
+				{Pattern: `^>`, Type: chroma.GenericPrompt, Mutator: chroma.Pop(1)},
+				// otherwise its all output
+				{Pattern: `[^\n]+$\n?`, Type: chroma.GenericOutput, Mutator: nil},
+			},
+		}
+	},
+)
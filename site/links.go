@@ -0,0 +1,302 @@
+package site
+
+import (
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// mdTargetPat matches the targets of Markdown links in docs, like
+	// [тикеров](tickers), once code spans are gone.
+	mdTargetPat = regexp.MustCompile(`\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	codeSpanPat = regexp.MustCompile("`[^`]*`")
+
+	// attrLinkPat matches the attributes of generated files that link
+	// somewhere, cssURLPat the references of stylesheets, and idPat the
+	// IDs that fragments point to.
+	attrLinkPat = regexp.MustCompile(`\s(?:href|src)="([^"]*)"`)
+	cssURLPat   = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+	idPat       = regexp.MustCompile(`\sid="([^"]*)"`)
+)
+
+// linkRef is where a link is: a file relative to the root and a line, 0
+// if it isn't known.
+type linkRef struct {
+	file string
+	line int
+}
+
+// externalLinks collects links to other sites by URL, without fragments.
+type externalLinks map[string][]linkRef
+
+func (e externalLinks) add(u *url.URL, ref linkRef) {
+	target := *u
+	target.Fragment = ""
+	e[target.String()] = append(e[target.String()], ref)
+}
+
+// CheckLinks checks the links of a site. The Markdown links in the doc
+// comments of every locale must point to examples listed in its
+// examples.txt, or its index or changelog, and their fragments to
+// segments or sections that exist. Then the site generated into outDir is
+// crawled: every href and src of its pages and feed, including those from
+// the templates, and every url() of its stylesheets must point to a file
+// in outDir, and fragments to an ID in the page. Links to the base URL of
+// site.json count as links into outDir.
+//
+// Links to other sites are checked only with a client, which makes a HEAD
+// request, or a GET one where HEAD fails, for each of them.
+func CheckLinks(cfg Config, outDir string, client *http.Client) error {
+	b := newBuilder(cfg)
+	external := make(externalLinks)
+	for _, lb := range b.locales() {
+		lb.checkDocLinks(external)
+	}
+	b.crawl(outDir, external)
+	if client != nil {
+		b.checkExternal(client, external)
+	}
+	return b.err()
+}
+
+// checkDocLinks checks the Markdown links in the docs of the locale's
+// examples, and adds those to other sites to external.
+func (b *builder) checkDocLinks(external externalLinks) {
+	examples := b.readExampleList()
+	listed := make(map[string]*Example)
+	for _, example := range examples {
+		listed[example.ID] = example
+	}
+	sections := make(map[string]bool)
+	for _, s := range listSections(examples) {
+		sections[s.ID] = true
+	}
+	anchors := make(map[string]map[string]bool)
+	anchorsOf := func(example *Example) map[string]bool {
+		if anchors[example.ID] == nil {
+			anchors[example.ID] = make(map[string]bool)
+			for _, anchor := range segAnchors(b.readSegs(example)) {
+				anchors[example.ID][anchor] = true
+			}
+		}
+		return anchors[example.ID]
+	}
+
+	for _, example := range examples {
+		paths := b.sourcePaths(b.exampleDir(example.ID))
+		for i, segs := range b.readSegs(example) {
+			path := paths[i]
+			var lines []string
+			for _, seg := range segs {
+				for _, m := range mdTargetPat.FindAllStringSubmatch(codeSpanPat.ReplaceAllString(seg.Docs, ""), -1) {
+					target := m[1]
+					if lines == nil {
+						lines = b.readLines(path)
+					}
+					ref := linkRef{path, 0}
+					if b.catalog != nil {
+						ref.file = b.catalog.path
+					} else {
+						for n, line := range lines {
+							if strings.Contains(line, "]("+target) {
+								ref.line = n + 1
+								break
+							}
+						}
+					}
+
+					u, err := url.Parse(target)
+					switch {
+					case err != nil:
+						b.diags.Errorf(ref.file, ref.line, "invalid link %q: %v", target, err)
+						continue
+					case u.Scheme == "http" || u.Scheme == "https":
+						external.add(u, ref)
+						continue
+					case u.Scheme != "" || u.Host != "":
+						continue
+					}
+
+					page := strings.TrimPrefix(u.Path, "./")
+					switch to := listed[page]; {
+					case page == "" && u.Path == "":
+						if u.Fragment != "" && !anchorsOf(example)[u.Fragment] {
+							b.diags.Errorf(ref.file, ref.line, "link to #%s, which isn't a segment of %s", u.Fragment, example.ID)
+						}
+					case page == "":
+						if u.Fragment != "" && !sections[u.Fragment] {
+							b.diags.Errorf(ref.file, ref.line, "link to #%s, which isn't a section of the index", u.Fragment)
+						}
+					case page == ChangelogFile:
+					case to == nil:
+						b.diags.Errorf(ref.file, ref.line, "link to %q, which isn't an example listed in examples.txt", target)
+					case u.Fragment != "" && !anchorsOf(to)[u.Fragment]:
+						b.diags.Errorf(ref.file, ref.line, "link to #%s, which isn't a segment of %s", u.Fragment, to.ID)
+					}
+				}
+			}
+		}
+	}
+}
+
+// crawl checks the links between the files generated into outDir, and
+// adds those to other sites to external.
+func (b *builder) crawl(outDir string, external externalLinks) {
+	files := make(map[string]bool)
+	ids := make(map[string]map[string]bool)
+	contents := make(map[string][]byte)
+	err := filepath.WalkDir(outDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		files[rel] = true
+		switch path.Ext(rel) {
+		case "", ".html":
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			contents[rel] = data
+			ids[rel] = make(map[string]bool)
+			for _, m := range idPat.FindAllSubmatch(data, -1) {
+				ids[rel][html.UnescapeString(string(m[1]))] = true
+			}
+		case ".atom", ".css":
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			contents[rel] = data
+		}
+		return nil
+	})
+	if b.failed(outDir, err) {
+		return
+	}
+
+	base, _ := url.Parse(b.settings.BaseURL)
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data := contents[name]
+		pat := attrLinkPat
+		if path.Ext(name) == ".css" {
+			pat = cssURLPat
+		}
+		for _, m := range pat.FindAllSubmatchIndex(data, -1) {
+			target := html.UnescapeString(string(data[m[2]:m[3]]))
+			ref := linkRef{filepath.ToSlash(filepath.Join(outDir, name)), lineAt(data, int64(m[2]))}
+			u, err := url.Parse(target)
+			if err != nil {
+				b.diags.Errorf(ref.file, ref.line, "invalid link %q: %v", target, err)
+				continue
+			}
+			if b.settings.BaseURL != "" && u.Host == base.Host && strings.HasPrefix(u.Path, base.Path) {
+				u = &url.URL{Path: "/" + strings.TrimPrefix(u.Path, base.Path), RawQuery: u.RawQuery, Fragment: u.Fragment}
+			}
+			switch {
+			case u.Scheme == "http" || u.Scheme == "https":
+				external.add(u, ref)
+				continue
+			case u.Scheme != "" || u.Host != "":
+				continue
+			}
+
+			page := strings.TrimPrefix((&url.URL{Path: "/" + name}).ResolveReference(u).Path, "/")
+			if page == "" || strings.HasSuffix(page, "/") || files[page+"/index.html"] {
+				page = strings.TrimSuffix(page, "/")
+				if page != "" {
+					page += "/"
+				}
+				page += "index.html"
+			}
+			switch {
+			case !files[page]:
+				b.diags.Errorf(ref.file, ref.line, "broken link to %q, there is no %s", target, page)
+			case u.Fragment != "" && ids[page] != nil && !ids[page][u.Fragment]:
+				b.diags.Errorf(ref.file, ref.line, "broken link to %q, %s has no ID %q", target, page, u.Fragment)
+			}
+		}
+	}
+}
+
+// checkExternal requests the links to other sites with client, a few at a
+// time, and reports those that fail where they are first linked from.
+func (b *builder) checkExternal(client *http.Client, external externalLinks) {
+	targets := make([]string, 0, len(external))
+	for target := range external {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < max(b.cfg.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				b.logf("Checking %s", target)
+				refs := external[target]
+				more := ""
+				if len(refs) > 1 {
+					more = fmt.Sprintf(" (linked %d times)", len(refs))
+				}
+				status, err := requestLink(client, target)
+				switch {
+				case err != nil:
+					b.diags.Errorf(refs[0].file, refs[0].line, "link to %s%s: %v", target, more, err)
+				case status == http.StatusTooManyRequests:
+					b.diags.Warnf(refs[0].file, refs[0].line, "link to %s%s: rate limited, try again later", target, more)
+				case status >= 400:
+					b.diags.Errorf(refs[0].file, refs[0].line, "broken link to %s%s: %d %s", target, more, status, http.StatusText(status))
+				}
+			}
+		}()
+	}
+	for _, target := range targets {
+		jobs <- target
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// requestLink returns the status of a HEAD request for target, or of a GET
+// request if the server doesn't take HEAD requests for it.
+func requestLink(client *http.Client, target string) (int, error) {
+	status := 0
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, target, nil)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+		if status < 400 || status == http.StatusTooManyRequests {
+			break
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,138 @@
+package site
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeFiles writes files, given as pairs of paths and contents, below dir.
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for i := 0; i+1 < len(files); i += 2 {
+		path := filepath.Join(dir, files[i])
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkDiags fails unless the diagnostics are errors with the wanted
+// messages, in any order.
+func checkDiags(t *testing.T, diags *Diagnostics, want ...string) {
+	t.Helper()
+	var got []string
+	for _, d := range diags.List() {
+		got = append(got, d.String())
+	}
+	missing := false
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			found = found || strings.Contains(g, w)
+		}
+		if !found {
+			t.Errorf("no diagnostic %q", w)
+			missing = true
+		}
+	}
+	if missing || len(got) != len(want) {
+		t.Errorf("got %d diagnostics, want %d:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+}
+
+func TestCheckDocLinks(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root,
+		"examples.txt", "## Basics\nhello|Hello\nworld|World\n",
+		"examples/hello/hello.go", "// Say [hello to the world](world),\n"+
+			"// see [main](world#main), [`main`](#main) and the\n"+
+			"// [basics](./#basics), but not [missing](nope),\n"+
+			"// [nothing](world#nothing) or [a section](./#advanced).\n"+
+			"package main\n\nfunc main() {}\n",
+		"examples/world/world.go", "// See [the changes](changelog) and [Go](https://go.dev/).\n"+
+			"package main\n\nfunc main() {}\n")
+	diags := &Diagnostics{}
+	b := newBuilder(Config{Root: root, Diagnostics: diags, Settings: DefaultSettings()})
+	external := make(externalLinks)
+	b.checkDocLinks(external)
+
+	checkDiags(t, diags,
+		`examples/hello/hello.go:3: error: link to "nope"`,
+		`examples/hello/hello.go:4: error: link to #nothing, which isn't a segment of world`,
+		`examples/hello/hello.go:4: error: link to #advanced, which isn't a section`)
+	if refs := external["https://go.dev/"]; len(refs) != 1 || refs[0].line != 1 {
+		t.Errorf("external links = %v, want https://go.dev/ on line 1 of world.go", external)
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/ok":
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	writeFiles(t, root, "examples.txt", "")
+	out := filepath.Join(root, "public")
+	writeFiles(t, out,
+		"index.html", `<a href="hello">Hello</a> <a href="hello#greet">greet</a>
+<a href="hello#nope">nope</a> <a href="gone">gone</a>
+<link rel=stylesheet href="site.css?v=1">
+<a href="`+server.URL+`/ok">ok</a> <a href="`+server.URL+`/ok#top">ok</a>
+<a href="`+server.URL+`/get-only">get</a> <a href="`+server.URL+`/missing">missing</a>
+<a href="mailto:someone@example.com">mail</a>`,
+		"hello", `<h2 id="hello"><a href="./">Index</a></h2><tr id="greet"><img src="play.png"></tr>`,
+		"site.css", `body { background: url("bg.png"); }`,
+		"en/index.html", `<a href="../hello">Hello</a> <a href="../en/">English</a> <a href="./hello">nope</a>`)
+
+	diags := &Diagnostics{}
+	err := CheckLinks(Config{Root: root, Diagnostics: diags, Settings: DefaultSettings()}, out, server.Client())
+	if err == nil {
+		t.Error("CheckLinks succeeded with broken links")
+	}
+	checkDiags(t, diags,
+		`public/index.html:2: error: broken link to "hello#nope", hello has no ID "nope"`,
+		`public/index.html:2: error: broken link to "gone", there is no gone`,
+		`public/index.html:5: error: broken link to `+server.URL+`/missing: 404 Not Found`,
+		`public/hello:1: error: broken link to "play.png", there is no play.png`,
+		`public/site.css:1: error: broken link to "bg.png", there is no bg.png`,
+		`public/en/index.html:1: error: broken link to "./hello", there is no en/hello`)
+
+	// /ok is requested once for both of its links.
+	n := 0
+	for _, r := range requests {
+		if strings.HasSuffix(r, " /ok") {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("requests = %v, want one for /ok", requests)
+	}
+
+	diags = &Diagnostics{}
+	CheckLinks(Config{Root: root, Diagnostics: diags, Settings: DefaultSettings()}, out, nil)
+	if len(diags.List()) != 5 {
+		t.Errorf("without a client got %d diagnostics, want the 5 internal ones: %v", len(diags.List()), diags.List())
+	}
+}
@@ -0,0 +1,219 @@
+package site
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Locale is a translation of the site. The primary locale is the one in the
+// root; the others are listed in Settings.Locales and live in a directory of
+// their own with an examples.txt, an examples/ tree of the same sources with
+// translated comments, and optionally templates/ overriding the root ones.
+// Instead of the examples/ tree, a locale can have a PO catalog with the
+// translations of the doc comments of the root examples, see Extract.
+//
+// Locales share the code, the .hash files and the assets of the primary
+// locale, so a translated example must have the same code as the original.
+type Locale struct {
+	// Language is the BCP 47 tag of the translation, used for the lang
+	// attribute and the hreflang links.
+	Language string `json:"language"`
+
+	// Name is the name of the language in the language switcher, e.g.
+	// "English".
+	Name string `json:"name"`
+
+	// Title replaces Settings.Title on the pages of the locale.
+	Title string `json:"title"`
+
+	// Dir is the directory of the locale's sources, relative to the root.
+	Dir string `json:"dir"`
+
+	// Path is the directory the locale is rendered into below the output
+	// directory and the path prefix of its pages.
+	Path string `json:"path"`
+
+	// Catalog is the path of the locale's PO catalog relative to the root,
+	// or empty for a locale with its own examples/.
+	Catalog string `json:"catalog"`
+}
+
+// Alternate is a version of a page in one of the locales, for the hreflang
+// links and the language switcher.
+type Alternate struct {
+	Language, Name, Href string
+
+	// Current is set for the locale of the page it's listed on.
+	Current bool
+}
+
+var localePathPat = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// primaryLocale describes the translation in the root.
+func (s *Settings) primaryLocale() *Locale {
+	return &Locale{Language: s.Language, Name: s.LanguageName, Title: s.Title}
+}
+
+// forLocale returns a builder that reads the sources and templates of loc,
+// and its catalog if it has one.
+func (b *builder) forLocale(loc *Locale) *builder {
+	lb := *b
+	lb.locale = loc
+	lb.catalog = nil
+	if loc.Catalog != "" {
+		lb.catalog = lb.readCatalog(loc.Catalog, false)
+		if lb.catalog == nil {
+			lb.catalog = &catalog{path: loc.Catalog}
+		}
+	}
+	return &lb
+}
+
+// locales returns a builder for every locale, the primary one first.
+func (b *builder) locales() []*builder {
+	builders := []*builder{b}
+	for i := range b.settings.Locales {
+		builders = append(builders, b.forLocale(&b.settings.Locales[i]))
+	}
+	return builders
+}
+
+func (b *builder) isPrimary() bool {
+	return b.locale.Dir == ""
+}
+
+// src turns a path relative to the locale's directory into one relative to
+// the root.
+func (b *builder) src(rel string) string {
+	return filepath.Join(b.locale.Dir, rel)
+}
+
+// exampleDir returns the directory of an example's sources: the locale's
+// copy, or the root one for a locale translated with a catalog.
+func (b *builder) exampleDir(id string) string {
+	if b.catalog != nil {
+		return "examples/" + id
+	}
+	return b.src("examples/" + id)
+}
+
+// templatePath returns the root-relative path of a file from templates/,
+// preferring the locale's own copy.
+func (b *builder) templatePath(file string) string {
+	if !b.isPrimary() {
+		if path := b.src("templates/" + file); b.exists(path) {
+			return path
+		}
+	}
+	return "templates/" + file
+}
+
+// alternateHref returns the link from a page of locale from to the page of
+// locale to; page is an example ID, or "" for the index. The link is
+// absolute when the site has a base URL.
+func alternateHref(baseURL string, from, to *Locale, page string) string {
+	href := page
+	if to.Path != "" {
+		href = to.Path + "/" + page
+	}
+	if baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/") + "/" + href
+	}
+	if from.Path != "" {
+		href = "../" + href
+	}
+	if href == "" {
+		href = "./"
+	}
+	return href
+}
+
+// alternates lists the versions of page in every locale that has it.
+func alternates(baseURL string, from *Locale, all []*builder, page string, has func(int) bool) []Alternate {
+	var alts []Alternate
+	for i, lb := range all {
+		if !has(i) {
+			continue
+		}
+		alts = append(alts, Alternate{
+			Language: lb.locale.Language,
+			Name:     lb.locale.Name,
+			Href:     alternateHref(baseURL, from, lb.locale, page),
+			Current:  lb.locale == from,
+		})
+	}
+	return alts
+}
+
+// linkAlternates sets the alternates of the example pages of every locale,
+// given the example lists in the order of all. Pages that aren't translated
+// have none.
+func linkAlternates(baseURL string, all []*builder, lists [][]*Example) {
+	if len(all) < 2 {
+		return
+	}
+	ids := make([]map[string]bool, len(lists))
+	for i, examples := range lists {
+		ids[i] = make(map[string]bool)
+		for _, example := range examples {
+			ids[i][example.ID] = true
+		}
+	}
+	for i, examples := range lists {
+		for _, example := range examples {
+			alts := alternates(baseURL, all[i].locale, all, example.ID, func(j int) bool {
+				return ids[j][example.ID]
+			})
+			if len(alts) > 1 {
+				example.Alternates = alts
+			}
+		}
+	}
+}
+
+// indexAlternates lists the index pages of all locales.
+func indexAlternates(baseURL string, from *Locale, all []*builder) []Alternate {
+	if len(all) < 2 {
+		return nil
+	}
+	return alternates(baseURL, from, all, "", func(int) bool { return true })
+}
+
+// codeLines returns the lines of a source file that aren't doc comments or
+// blank, with their line numbers.
+func (b *builder) codeLines(path string) ([]string, []int) {
+	var lines []string
+	var lineNos []int
+	for i, line := range b.readLines(path) {
+		if !IsDocLine(line) && strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+			lineNos = append(lineNos, i+1)
+		}
+	}
+	return lines, lineNos
+}
+
+// checkSharedCode reports the sources of a translated example whose code
+// differs from the primary locale's, which the run links and .hash files
+// are shared with.
+func (b *builder) checkSharedCode(sourcePath string) {
+	original := filepath.Join("examples", filepath.Base(filepath.Dir(sourcePath)), filepath.Base(sourcePath))
+	if !b.exists(original) {
+		b.diags.Errorf(sourcePath, 0, "no %s to share the code with", filepath.ToSlash(original))
+		return
+	}
+	want, _ := b.codeLines(original)
+	got, lineNos := b.codeLines(sourcePath)
+	for i := range max(len(want), len(got)) {
+		switch {
+		case i >= len(got):
+			b.diags.Errorf(sourcePath, 0, "code ends before %s does", filepath.ToSlash(original))
+		case i >= len(want) || want[i] != got[i]:
+			b.diags.Errorf(sourcePath, lineNos[i], "code differs from %s, translations may only change comments", filepath.ToSlash(original))
+		default:
+			continue
+		}
+		return
+	}
+}
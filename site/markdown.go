@@ -0,0 +1,136 @@
+package site
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MarkdownIndexFile is the file that the "markdown" export lists the
+// examples in, next to a .md file per example.
+const MarkdownIndexFile = "README.md"
+
+// docLinkPat matches the inline links of Markdown docs, and the code spans
+// that look like them but aren't.
+var docLinkPat = regexp.MustCompile("`[^`]*`|\\[([^\\[\\]]*)\\]\\(([^()\\s]*)\\)")
+
+// markdownData arranges parsed examples into the sections and files of the
+// Markdown export. The docs of its segments are Markdown with the links
+// pointing to the exported files, and the code a fenced block. Segments
+// without docs are joined to the code block before them.
+func (b *builder) markdownData(examples []*Example) *BookData {
+	data := &BookData{Site: b.siteConfig()}
+	sections := listSections(examples)
+	if sections == nil {
+		sections = []*Section{{Examples: examples}}
+	}
+	listed := make(map[string]bool)
+	for _, example := range examples {
+		listed[example.ID] = true
+	}
+
+	for _, s := range sections {
+		section := &BookSection{ID: s.ID, Title: s.Title, Href: MarkdownIndexFile + "#" + s.ID}
+		for _, example := range s.Examples {
+			chapter := &BookChapter{Example: example, ID: example.ID, Href: example.ID + ".md"}
+			for _, sourceSegs := range example.Segs {
+				var segs []*BookSeg
+				var code []string
+				var goCode bool
+				endBlock := func() {
+					if len(code) > 0 {
+						segs[len(segs)-1].Code = codeFence(strings.Join(code, "\n\n"), goCode)
+					}
+					code = nil
+				}
+				for _, seg := range sourceSegs {
+					if seg.Docs != "" || len(segs) == 0 {
+						endBlock()
+						docs := b.markdownLinks(strings.TrimSpace(seg.Docs), listed, ".md", MarkdownIndexFile)
+						segs = append(segs, &BookSeg{ID: seg.Anchor, Docs: docs, CodeEmpty: true})
+					}
+					if c := strings.Trim(seg.Code, "\n"); c != "" {
+						code = append(code, c)
+						goCode = seg.goCode
+						segs[len(segs)-1].CodeEmpty = false
+					}
+				}
+				endBlock()
+				chapter.Segs = append(chapter.Segs, segs)
+			}
+			section.Chapters = append(section.Chapters, chapter)
+		}
+		data.Sections = append(data.Sections, section)
+	}
+	return data
+}
+
+// codeFence returns code as a fenced block of Go or of a shell session,
+// fenced with more backticks than any run of them in the code.
+func codeFence(code string, goCode bool) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	lang := "console"
+	if goCode {
+		lang = "go"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// markdownLinks points the links of an example's Markdown docs to the
+// exported files: links to examples go to their files with the extension
+// ext, the index and its sections to the index file, and the other pages
+// of the site to their absolute URLs, as does the index without an index
+// file. Without a base URL to make those from, only the link text is kept.
+// Absolute links and links within the example are left alone.
+func (b *builder) markdownLinks(docs string, listed map[string]bool, ext, index string) string {
+	return docLinkPat.ReplaceAllStringFunc(docs, func(link string) string {
+		m := docLinkPat.FindStringSubmatch(link)
+		if strings.HasPrefix(link, "`") || m[2] == "" || strings.HasPrefix(m[2], "#") || strings.Contains(m[2], "//") {
+			return link
+		}
+		page, fragment, _ := strings.Cut(m[2], "#")
+		target := ""
+		switch {
+		case (page == "./" || page == ".") && index != "":
+			target = index
+		case page == "./" || page == ".":
+			target = b.pageURL("")
+		case listed[page]:
+			target = page + ext
+		case strings.Contains(page, ":"):
+			return link
+		default:
+			target = b.pageURL(page)
+		}
+		if target == "" {
+			return m[1]
+		}
+		if fragment != "" {
+			target += "#" + fragment
+		}
+		return "[" + m[1] + "](" + target + ")"
+	})
+}
+
+// writeMarkdown writes a Markdown file per example, with its docs as prose
+// and its code and transcripts as fenced blocks, and MarkdownIndexFile
+// listing them in the order of examples.txt.
+func (b *builder) writeMarkdown(examples []*Example, outDir string) {
+	b.logf("Rendering Markdown")
+	exampleTmpl := b.parseTemplates("markdown", "markdown.tmpl")
+	indexTmpl := b.parseTemplates("markdown-index", "markdown-index.tmpl")
+	if exampleTmpl == nil || indexTmpl == nil {
+		return
+	}
+	data := b.markdownData(examples)
+	for _, section := range data.Sections {
+		for _, chapter := range section.Chapters {
+			page := *data
+			page.Chapter = chapter
+			b.renderPage(exampleTmpl, b.templatePath("markdown.tmpl"), outDir, chapter.Href, &page)
+		}
+	}
+	b.renderPage(indexTmpl, b.templatePath("markdown-index.tmpl"), outDir, MarkdownIndexFile, data)
+}
@@ -0,0 +1,39 @@
+package site
+
+import "testing"
+
+func TestMarkdownLinks(t *testing.T) {
+	settings := DefaultSettings()
+	settings.BaseURL = "https://example.com/"
+	b := newBuilder(Config{Root: t.TempDir(), Settings: settings})
+	listed := map[string]bool{"tickers": true}
+	tests := []struct {
+		docs, want string
+	}{
+		{"Like [тикеров](tickers).", "Like [тикеров](tickers.md)."},
+		{"[Stop](tickers#stop) it.", "[Stop](tickers.md#stop) it."},
+		{"Back to [the start](./#basics).", "Back to [the start](README.md#basics)."},
+		{"See [the index](api#fmt).", "See [the index](https://example.com/api#fmt)."},
+		{"[Go](https://go.dev/) and [above](#main).", "[Go](https://go.dev/) and [above](#main)."},
+		{"Call `s[i](tickers)` here.", "Call `s[i](tickers)` here."},
+	}
+	for _, tt := range tests {
+		if got := b.markdownLinks(tt.docs, listed, ".md", MarkdownIndexFile); got != tt.want {
+			t.Errorf("markdownLinks(%q) = %q, want %q", tt.docs, got, tt.want)
+		}
+	}
+
+	b.settings.BaseURL = ""
+	if got, want := b.markdownLinks("See [the index](api).", listed, ".md", MarkdownIndexFile), "See the index."; got != want {
+		t.Errorf("markdownLinks without a base URL = %q, want %q", got, want)
+	}
+}
+
+func TestCodeFence(t *testing.T) {
+	if got, want := codeFence("$ go run x.go", false), "```console\n$ go run x.go\n```"; got != want {
+		t.Errorf("codeFence = %q, want %q", got, want)
+	}
+	if got, want := codeFence("s := `a```b`", true), "````go\ns := `a```b`\n````"; got != want {
+		t.Errorf("codeFence = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package site
+
+import (
+	"encoding/json"
+	"go/version"
+	"slices"
+	"strings"
+)
+
+// MetaFile is the optional file in an example's directory that says what
+// its sources don't, see Meta.
+const MetaFile = "meta.json"
+
+// The values Meta.Difficulty and Meta.Requires can take.
+var (
+	difficulties = []string{"beginner", "intermediate", "advanced"}
+	requirements = []string{"network", "files", "signals", "processes"}
+)
+
+// Meta is the metadata of an example, read from examples/<id>/meta.json.
+// All of it is optional, and locales share the file like the code.
+type Meta struct {
+	// GoVersion is the oldest Go release the example works with, e.g.
+	// "1.23". The generator works that out from the code, see
+	// Example.MinGo, and fails if the two disagree.
+	GoVersion string `json:"goVersion,omitempty"`
+
+	// Tags are the topics of the example, lowercase words joined by dashes.
+	Tags []string `json:"tags,omitempty"`
+
+	// Difficulty is "beginner", "intermediate" or "advanced".
+	Difficulty string `json:"difficulty,omitempty"`
+
+	// Playground is false for an example that doesn't run on the
+	// playground. It isn't shared, and its page has no run button; a
+	// .hash file it has is left alone.
+	Playground *bool `json:"playground,omitempty"`
+
+	// Requires lists what the example needs from the machine it runs on:
+	// "network", "files", "signals" or "processes".
+	Requires []string `json:"requires,omitempty"`
+
+	// Related are the IDs of other examples worth reading with this one.
+	Related []string `json:"related,omitempty"`
+}
+
+// Runnable reports whether the example runs on the playground.
+func (m Meta) Runnable() bool {
+	return m.Playground == nil || *m.Playground
+}
+
+// metaPath returns the path of an example's metadata file, which is in
+// the root examples/ for every locale.
+func metaPath(id string) string {
+	return "examples/" + id + "/" + MetaFile
+}
+
+// readMeta reads the metadata files of examples and links the related
+// examples they name. The primary locale reports what is wrong with the
+// files; the others, which read the same ones, drop the related examples
+// they don't list.
+func (b *builder) readMeta(examples []*Example) {
+	listed := make(map[string]*Example)
+	for _, example := range examples {
+		listed[example.ID] = example
+	}
+	for _, example := range examples {
+		path := metaPath(example.ID)
+		if !b.exists(path) {
+			continue
+		}
+		data := []byte(b.readFile(path))
+		var meta Meta
+		if b.isPrimary() {
+			if !b.decodeJSON(path, data, &meta) {
+				continue
+			}
+			b.checkMeta(path, example.ID, &meta, listed)
+		} else if json.Unmarshal(data, &meta) != nil {
+			continue
+		}
+		example.Meta = meta
+		example.Related = nil
+		for _, id := range meta.Related {
+			if related := listed[id]; related != nil && related != example && !slices.Contains(example.Related, related) {
+				example.Related = append(example.Related, related)
+			}
+		}
+	}
+}
+
+func (b *builder) checkMeta(path, id string, meta *Meta, listed map[string]*Example) {
+	if v := meta.GoVersion; v != "" && (strings.HasPrefix(v, "go") || !version.IsValid("go"+v)) {
+		b.diags.Errorf(path, 0, "goVersion %q isn't a Go release like \"1.23\"", v)
+	}
+	if d := meta.Difficulty; d != "" && !slices.Contains(difficulties, d) {
+		b.diags.Errorf(path, 0, "unknown difficulty %q, expected one of %s", d, strings.Join(difficulties, ", "))
+	}
+	seen := make(map[string]bool)
+	for _, tag := range meta.Tags {
+		if !slugPat.MatchString(tag) {
+			b.diags.Errorf(path, 0, "invalid tag %q, expected lowercase words joined by dashes", tag)
+		} else if seen["tag:"+tag] {
+			b.diags.Errorf(path, 0, "duplicate tag %q", tag)
+		}
+		seen["tag:"+tag] = true
+	}
+	for _, req := range meta.Requires {
+		if !slices.Contains(requirements, req) {
+			b.diags.Errorf(path, 0, "unknown requirement %q, expected one of %s", req, strings.Join(requirements, ", "))
+		} else if seen["requires:"+req] {
+			b.diags.Errorf(path, 0, "duplicate requirement %q", req)
+		}
+		seen["requires:"+req] = true
+	}
+	for _, related := range meta.Related {
+		switch {
+		case related == id:
+			b.diags.Errorf(path, 0, "the example is related to itself")
+		case listed[related] == nil:
+			b.diags.Errorf(path, 0, "related example %q isn't listed in examples.txt", related)
+		case seen["related:"+related]:
+			b.diags.Errorf(path, 0, "duplicate related example %q", related)
+		}
+		seen["related:"+related] = true
+	}
+}
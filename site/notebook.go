@@ -0,0 +1,273 @@
+package site
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The notebooks of the "ipynb" export are in nbformat 4, for a Go kernel
+// like GoNB that keeps the declarations of earlier cells and runs main
+// when a cell declares it.
+const (
+	notebookFormat      = 4
+	notebookFormatMinor = 4
+)
+
+// notebook is the JSON of a Jupyter notebook. Its cells are markdownCell
+// and codeCell values.
+type notebook struct {
+	Cells         []any            `json:"cells"`
+	Metadata      notebookMetadata `json:"metadata"`
+	NBFormat      int              `json:"nbformat"`
+	NBFormatMinor int              `json:"nbformat_minor"`
+}
+
+type notebookMetadata struct {
+	KernelSpec   notebookKernel   `json:"kernelspec"`
+	LanguageInfo notebookLanguage `json:"language_info"`
+}
+
+type notebookKernel struct {
+	DisplayName string `json:"display_name"`
+	Language    string `json:"language"`
+	Name        string `json:"name"`
+}
+
+type notebookLanguage struct {
+	FileExtension string `json:"file_extension"`
+	MimeType      string `json:"mimetype"`
+	Name          string `json:"name"`
+}
+
+type markdownCell struct {
+	CellType string   `json:"cell_type"`
+	Metadata struct{} `json:"metadata"`
+	Source   []string `json:"source"`
+}
+
+type codeCell struct {
+	CellType       string         `json:"cell_type"`
+	ExecutionCount *int           `json:"execution_count"`
+	Metadata       struct{}       `json:"metadata"`
+	Outputs        []streamOutput `json:"outputs"`
+	Source         []string       `json:"source"`
+}
+
+// streamOutput is the stored output of a code cell, for the transcript of
+// running the program.
+type streamOutput struct {
+	Name       string   `json:"name"`
+	OutputType string   `json:"output_type"`
+	Text       []string `json:"text"`
+}
+
+// cellSource splits text into the lines of a cell's source, each but the
+// last with its newline.
+func cellSource(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// notebookCells holds the cells of a notebook as they're added.
+type notebookCells struct {
+	cells []any
+
+	// main is the cell that declares main, which the output of running
+	// the program is stored in.
+	main *codeCell
+}
+
+func (n *notebookCells) markdown(text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		n.cells = append(n.cells, &markdownCell{CellType: "markdown", Source: cellSource(text)})
+	}
+}
+
+func (n *notebookCells) code(text string, declaresMain bool) {
+	if text = strings.Trim(text, "\n"); text == "" {
+		return
+	}
+	cell := &codeCell{CellType: "code", Outputs: []streamOutput{}, Source: cellSource(text)}
+	n.cells = append(n.cells, cell)
+	if declaresMain && n.main == nil {
+		n.main = cell
+	}
+}
+
+// goCells adds the cells of a Go source: the docs of its segments become
+// markdown cells, and its code runs of code cells. A new code cell only
+// starts between top-level declarations, so that every cell compiles with
+// those before it; the docs of the segments within a cell stay comments
+// in its code. The package clause is left out, as kernels have none.
+func (b *builder) goCells(n *notebookCells, path string, segs []*Seg, listed map[string]bool) {
+	lines := b.readLines(path)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, strings.Join(lines, "\n"), parser.SkipObjectResolution)
+	if err != nil {
+		b.diags.Warnf(path, 0, "can't split the code into notebook cells: %v", err)
+	}
+
+	// spans[i] is the index of the top-level declaration that line i is
+	// part of, or -1 between declarations.
+	spans := make([]int, len(lines))
+	for i := range spans {
+		spans[i] = -1
+		if file == nil {
+			spans[i] = 0
+		}
+	}
+	mainDecl := -1
+	if file != nil {
+		for i, decl := range file.Decls {
+			for line := fset.Position(decl.Pos()).Line; line <= fset.Position(decl.End()).Line; line++ {
+				spans[line-1] = i
+			}
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+				mainDecl = i
+			}
+		}
+	}
+
+	var code []string
+	declaresMain := false
+	lastLine := -1
+	endCell := func() {
+		n.code(strings.Join(code, "\n"), declaresMain)
+		code, declaresMain = nil, false
+	}
+	for k, seg := range segs {
+		// The segment continues the cell if the declaration of the last
+		// code goes on past it.
+		first := -1
+		for _, next := range segs[k:] {
+			if len(next.codeLines) > 0 {
+				first = next.codeLines[0]
+				break
+			}
+		}
+		within := lastLine >= 0 && first >= 0 && spans[lastLine] >= 0 && spans[lastLine] == spans[first]
+		docs := b.markdownLinks(strings.TrimSpace(seg.Docs), listed, ".ipynb", "")
+		if !within && (docs != "" || len(code) == 0) {
+			endCell()
+			n.markdown(docs)
+		} else {
+			if len(code) > 0 {
+				code = append(code, "")
+			}
+			if docs != "" {
+				indent := lines[first][:len(lines[first])-len(strings.TrimLeft(lines[first], " \t"))]
+				for _, line := range strings.Split(strings.TrimSpace(seg.Docs), "\n") {
+					code = append(code, strings.TrimRight(indent+"// "+line, " "))
+				}
+			}
+		}
+		for _, i := range seg.codeLines {
+			if !strings.HasPrefix(lines[i], "package ") {
+				code = append(code, lines[i])
+				declaresMain = declaresMain || mainDecl >= 0 && spans[i] == mainDecl
+			}
+			lastLine = i
+		}
+	}
+	endCell()
+}
+
+// runOutput returns the output of the first `go run` in a transcript, and
+// the segment it's in if the segment has nothing else.
+func runOutput(segs []*Seg) (string, *Seg) {
+	for _, seg := range segs {
+		lines := strings.Split(strings.Trim(seg.Code, "\n"), "\n")
+		for i, line := range lines {
+			if !strings.HasPrefix(line, "$ go run ") {
+				continue
+			}
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(lines[end], "$ ") {
+				end++
+			}
+			out := strings.Join(lines[i+1:end], "\n")
+			if i == 0 && end == len(lines) {
+				return out, seg
+			}
+			return out, nil
+		}
+	}
+	return "", nil
+}
+
+// exampleNotebook returns the notebook of a parsed example: its title, the
+// cells of its Go sources, and its transcripts as markdown cells. The
+// output of the first `go run` in them is stored in the cell that
+// declares main, and left out of the markdown if it's a segment of its
+// own.
+func (b *builder) exampleNotebook(example *Example, listed map[string]bool) *notebook {
+	n := &notebookCells{}
+	n.markdown("# " + example.Title)
+	var transcripts [][]*Seg
+	for i, path := range b.sourcePaths(b.exampleDir(example.ID)) {
+		if i >= len(example.Segs) {
+			break
+		}
+		if filepath.Ext(path) == ".go" {
+			b.goCells(n, path, example.Segs[i], listed)
+		} else {
+			transcripts = append(transcripts, example.Segs[i])
+		}
+	}
+	for _, segs := range transcripts {
+		out, run := runOutput(segs)
+		if n.main != nil && strings.TrimSpace(out) != "" {
+			n.main.Outputs = append(n.main.Outputs, streamOutput{"stdout", "stream", cellSource(out + "\n")})
+			// Only the first run is stored.
+			n.main = nil
+		} else {
+			run = nil
+		}
+		for _, seg := range segs {
+			text := b.markdownLinks(strings.TrimSpace(seg.Docs), listed, ".ipynb", "")
+			if code := strings.Trim(seg.Code, "\n"); code != "" && seg != run {
+				text += "\n\n" + codeFence(code, false)
+			}
+			n.markdown(text)
+		}
+	}
+	return &notebook{
+		Cells: n.cells,
+		Metadata: notebookMetadata{
+			KernelSpec:   notebookKernel{DisplayName: "Go (gonb)", Language: "go", Name: "gonb"},
+			LanguageInfo: notebookLanguage{FileExtension: ".go", MimeType: "text/x-go", Name: "go"},
+		},
+		NBFormat:      notebookFormat,
+		NBFormatMinor: notebookFormatMinor,
+	}
+}
+
+// writeNotebooks writes a Jupyter notebook per example.
+func (b *builder) writeNotebooks(examples []*Example, outDir string) {
+	b.logf("Rendering notebooks")
+	listed := make(map[string]bool)
+	for _, example := range examples {
+		listed[example.ID] = true
+	}
+	for _, example := range examples {
+		// Like Jupyter, keep <, > and & as they are.
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", " ")
+		path := filepath.Join(outDir, example.ID+".ipynb")
+		if b.failed(path, enc.Encode(b.exampleNotebook(example, listed))) {
+			continue
+		}
+		b.failed(path, os.WriteFile(path, buf.Bytes(), 0644))
+	}
+}
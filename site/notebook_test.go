@@ -0,0 +1,50 @@
+package site
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExampleNotebook(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root,
+		"examples.txt", "count|Count\n",
+		"examples/count/count.go", "// Counting, like [tickers](tickers).\npackage main\n\nimport \"fmt\"\n\n"+
+			"// A counter.\ntype counter int\n\nfunc (c *counter) inc() { *c++ }\n\n"+
+			"func main() {\n\tvar c counter\n\n\t// Count twice.\n\tc.inc()\n\tc.inc()\n\tfmt.Println(c)\n}\n",
+		"examples/count/count.sh", "# Run it.\n$ go run count.go\n2\n\n# Then build it.\n$ go build count.go\n")
+	b := newBuilder(Config{Root: root, Settings: DefaultSettings()})
+	examples := b.readExampleList()
+	b.parseSelected(examples)
+	if err := b.err(); err != nil {
+		t.Fatal(err)
+	}
+	nb := b.exampleNotebook(examples[0], map[string]bool{"count": true, "tickers": true})
+
+	var got []string
+	for _, cell := range nb.Cells {
+		switch cell := cell.(type) {
+		case *markdownCell:
+			got = append(got, "markdown: "+strings.Join(cell.Source, ""))
+		case *codeCell:
+			s := "code: " + strings.Join(cell.Source, "")
+			for _, out := range cell.Outputs {
+				s += "\n=> " + strings.Join(out.Text, "")
+			}
+			got = append(got, s)
+		}
+	}
+	want := []string{
+		"markdown: # Count",
+		"markdown: Counting, like [tickers](tickers.ipynb).",
+		"code: import \"fmt\"",
+		"markdown: A counter.",
+		"code: type counter int\n\nfunc (c *counter) inc() { *c++ }\n\n" +
+			"func main() {\n\tvar c counter\n\n\t// Count twice.\n\tc.inc()\n\tc.inc()\n\tfmt.Println(c)\n}\n=> 2\n",
+		"markdown: Run it.",
+		"markdown: Then build it.\n\n```console\n$ go build count.go\n```",
+	}
+	if strings.Join(got, "\n---\n") != strings.Join(want, "\n---\n") {
+		t.Errorf("cells:\n%s\nwant:\n%s", strings.Join(got, "\n---\n"), strings.Join(want, "\n---\n"))
+	}
+}
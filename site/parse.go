@@ -0,0 +1,489 @@
+package site
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+var docsPat = regexp.MustCompile(`^(\s*(\/\/|#)\s|\s*\/\/$)`)
+var dashPat = regexp.MustCompile(`\-+`)
+var slugPat = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// packagePat matches the package clause of a Go source. The segment with it
+// gets the run button, which runs all of the example's Go files.
+var packagePat = regexp.MustCompile(`(?m)^package \w+`)
+
+// IsDocLine reports whether a source line is part of the docs of a segment
+// rather than its code: a // comment in Go or a # comment in a transcript.
+func IsDocLine(line string) bool {
+	return docsPat.MatchString(line)
+}
+
+func markdown(src string) string {
+	return string(blackfriday.Run([]byte(src)))
+}
+
+// whichLexer returns the lexer for a source file, or "" after recording a
+// diagnostic if it's neither Go code nor a shell transcript.
+func (b *builder) whichLexer(path string) string {
+	if strings.HasSuffix(path, ".go") {
+		return "go"
+	} else if strings.HasSuffix(path, ".sh") {
+		return "console"
+	}
+	b.diags.Errorf(path, 0, "no lexer for this file, expected a .go or .sh file")
+	return ""
+}
+
+// segSpan is a segment of a source file as the indexes of its doc and code
+// lines. The code lines of a segment are always consecutive in the file.
+type segSpan struct {
+	docs, code []int
+}
+
+// segmentLines splits the lines of a source file into segments: a run of
+// doc comments and the code that follows it. A blank line ends a segment.
+func segmentLines(lines []string) []*segSpan {
+	spans := []*segSpan{}
+	// hasDocs mirrors checking the joined docs of the last segment for "":
+	// a single bare "//" line counts as no docs.
+	hasDocs := func(span *segSpan) bool {
+		return len(span.docs) > 1 || (len(span.docs) == 1 && docsPat.ReplaceAllString(lines[span.docs[0]], "") != "")
+	}
+	lastSeen := ""
+	for i, line := range lines {
+		if line == "" {
+			lastSeen = ""
+			continue
+		}
+		matchDocs := docsPat.MatchString(line)
+		matchCode := !matchDocs
+		newDocs := (lastSeen == "") || ((lastSeen != "docs") && hasDocs(spans[len(spans)-1]))
+		newCode := (lastSeen == "") || ((lastSeen != "code") && len(spans[len(spans)-1].code) > 0)
+		if newDocs || newCode {
+			debug("NEWSEG")
+		}
+		if matchDocs {
+			if newDocs {
+				spans = append(spans, &segSpan{})
+			}
+			last := spans[len(spans)-1]
+			last.docs = append(last.docs, i)
+			debug("DOCS: " + line)
+			lastSeen = "docs"
+		} else if matchCode {
+			if newCode {
+				spans = append(spans, &segSpan{})
+			}
+			last := spans[len(spans)-1]
+			last.code = append(last.code, i)
+			debug("CODE: " + line)
+			lastSeen = "code"
+		}
+	}
+	return spans
+}
+
+// joinLines joins the lines at the given indexes, mapping each with f.
+func joinLines(lines []string, indexes []int, f func(string) string) string {
+	parts := make([]string, len(indexes))
+	for k, i := range indexes {
+		parts[k] = f(lines[i])
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (b *builder) parseSegs(sourcePath string) ([]*Seg, string) {
+	source := b.readLines(sourcePath)
+	// Convert tabs to spaces for uniform rendering.
+	lines := make([]string, len(source))
+	for i, line := range source {
+		lines[i] = strings.Replace(line, "\t", "    ", -1)
+	}
+	segs := []*Seg{}
+	for _, span := range segmentLines(lines) {
+		segs = append(segs, &Seg{
+			Docs:      joinLines(lines, span.docs, func(line string) string { return docsPat.ReplaceAllString(line, "") }),
+			Code:      joinLines(lines, span.code, func(line string) string { return line }),
+			goCode:    strings.HasSuffix(sourcePath, ".go"),
+			codeLines: span.code,
+		})
+	}
+	for i, seg := range segs {
+		seg.CodeEmpty = (seg.Code == "")
+		seg.CodeLeading = (i < (len(segs) - 1))
+		seg.CodeRun = seg.goCode && packagePat.MatchString(seg.Code)
+	}
+	return segs, strings.Join(source, "\n")
+}
+
+// chromaLexers caches the coalesced lexer for each kind of source file, so
+// that lexers are looked up and their rules compiled once per run instead of
+// once per segment.
+var (
+	chromaLexers   = make(map[string]chroma.Lexer)
+	chromaLexersMu sync.Mutex
+)
+
+func chromaLexer(filePath string) chroma.Lexer {
+	ext := filepath.Ext(filePath)
+	chromaLexersMu.Lock()
+	defer chromaLexersMu.Unlock()
+	if lexer, ok := chromaLexers[ext]; ok {
+		return lexer
+	}
+	lexer := lexers.Get(filePath)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	if ext == ".sh" {
+		lexer = SimpleShellOutputLexer
+	}
+	lexer = chroma.Coalesce(lexer)
+	chromaLexers[ext] = lexer
+	return lexer
+}
+
+// chromaStyles caches styles by name.
+var (
+	chromaStyles   = make(map[string]*chroma.Style)
+	chromaStylesMu sync.Mutex
+)
+
+func chromaStyle(name string) *chroma.Style {
+	chromaStylesMu.Lock()
+	defer chromaStylesMu.Unlock()
+	if style, ok := chromaStyles[name]; ok {
+		return style
+	}
+	style := styles.Get(name)
+	if style == nil {
+		style = styles.Fallback
+	}
+	chromaStyles[name] = style
+	return style
+}
+
+// chromaFormatter is shared by all workers; the HTML formatter keeps no state
+// between calls to Format. chromaInlineFormatter writes the style of every
+// token into its style attribute instead of a class, for pages that can't
+// have chroma.css.
+var (
+	chromaFormatter       = html.New(html.WithClasses(true))
+	chromaInlineFormatter = html.New(html.WithClasses(false))
+)
+
+func (b *builder) chromaFormat(code, filePath string) string {
+	return b.chromaFormatWith(chromaFormatter, code, filePath)
+}
+
+func (b *builder) chromaFormatWith(formatter *html.Formatter, code, filePath string) string {
+	iterator, err := chromaLexer(filePath).Tokenise(nil, string(code))
+	if b.failed(filePath, err) {
+		return ""
+	}
+	buf := new(bytes.Buffer)
+	err = formatter.Format(buf, chromaStyle(b.settings.Style), iterator)
+	if b.failed(filePath, err) {
+		return ""
+	}
+	return buf.String()
+}
+
+// parseAndRenderSegs parses a source file into rendered segments; localize,
+// if not nil, translates their docs first. It also returns the code of a Go
+// source, for sharing.
+func (b *builder) parseAndRenderSegs(sourcePath string, localize func([]*Seg)) ([]*Seg, string) {
+	lexer := b.whichLexer(sourcePath)
+	if lexer == "" {
+		return nil, ""
+	}
+	segs, filecontent := b.parseSegs(sourcePath)
+	if localize != nil {
+		localize(segs)
+	}
+	for _, seg := range segs {
+		if seg.Docs != "" {
+			seg.DocsRendered = markdown(seg.Docs)
+		}
+		if seg.Code != "" {
+			seg.CodeRendered = b.chromaFormat(seg.Code, sourcePath)
+
+			// adding the content to the js code for copying to the clipboard
+			if strings.HasSuffix(sourcePath, ".go") {
+				seg.CodeForJs = strings.Trim(seg.Code, "\n") + "\n"
+			}
+		}
+	}
+	// we are only interested in the 'go' code to pass to play.golang.org
+	if lexer != "go" {
+		filecontent = ""
+	}
+	return segs, filecontent
+}
+
+// parseExample reads, parses and renders the sources of a single example
+// into e, which already has its ID and Title set.
+func (b *builder) parseExample(example *Example) {
+	example.Segs = make([][]*Seg, 0)
+	sourcePaths := b.glob(b.exampleDir(example.ID) + "/*")
+	segCount := 0
+	var goFiles []playgroundFile
+	goSegs := make(map[string][]*Seg)
+	for _, sourcePath := range sourcePaths {
+		if !b.isDir(sourcePath) {
+			if filepath.Base(sourcePath) == MetaFile {
+				if !b.isPrimary() && b.catalog == nil {
+					b.diags.Warnf(sourcePath, 0, "translations share the %s of examples/%s, ignoring this one", MetaFile, example.ID)
+				}
+			} else if strings.HasSuffix(sourcePath, ".hash") {
+				if !example.Meta.Runnable() {
+					continue
+				}
+				if !b.isPrimary() {
+					if b.catalog == nil {
+						b.diags.Warnf(sourcePath, 0, "translations share the .hash file of examples/%s, ignoring this one", example.ID)
+					}
+					continue
+				}
+				example.GoCodeHash, example.URLHash = b.parseHashFile(sourcePath)
+			} else {
+				var localize func([]*Seg)
+				if b.catalog != nil {
+					first := segCount
+					localize = func(segs []*Seg) { b.localizeSegs(example.ID, first, segs) }
+				} else if !b.isPrimary() {
+					b.checkSharedCode(sourcePath)
+				}
+				sourceSegs, filecontents := b.parseAndRenderSegs(sourcePath, localize)
+				if sourceSegs == nil {
+					continue
+				}
+				if filecontents != "" {
+					goFiles = append(goFiles, playgroundFile{filepath.Base(sourcePath), filecontents})
+					goSegs[filepath.Base(sourcePath)] = sourceSegs
+				}
+				example.Segs = append(example.Segs, sourceSegs)
+				segCount += len(sourceSegs)
+			}
+		}
+	}
+	if len(example.Segs) == 0 {
+		b.diags.Errorf(b.exampleDir(example.ID), 0, "no .go or .sh sources for example %q", example.ID)
+		return
+	}
+	example.GoCode = b.playgroundCode(b.exampleDir(example.ID), goFiles)
+	if len(goFiles) > 0 {
+		if info := b.analyzeGo(b.exampleDir(example.ID), goFiles); info != nil {
+			b.checkGoVersion(example, info)
+			b.linkStd(info, goSegs)
+			example.Packages = info.packages
+		}
+	}
+	setAnchors(example.ID, example.Segs)
+	example.Description = description(example.Segs)
+	if !example.Meta.Runnable() {
+		return
+	}
+	if !b.isPrimary() {
+		// The code is the primary locale's, and so is its run link, which
+		// that locale has just brought up to date.
+		if hashPath := "examples/" + example.ID + "/" + example.ID + ".hash"; b.exists(hashPath) {
+			example.GoCodeHash, example.URLHash = b.parseHashFile(hashPath)
+		}
+		return
+	}
+	newCodeHash := sha1Sum(example.GoCode)
+	if example.GoCodeHash != newCodeHash {
+		example.URLHash = b.resetURLHashFile(newCodeHash, example.GoCode, "examples/"+example.ID+"/"+example.ID+".hash")
+	}
+}
+
+// readExampleList reads examples.txt into examples that only have their ID,
+// Title and Meta set, linked to their neighbours, their sections and their
+// related examples. Invalid lines are reported and skipped.
+//
+// A line "## Title" starts a section, which the examples below it belong
+// to until the next one; other lines starting with "#" are comments. Once
+// the list has sections, every example must be in one.
+func (b *builder) readExampleList() []*Example {
+	examples := make([]*Example, 0)
+	seen := make(map[string]int)
+	listPath := b.src("examples.txt")
+
+	var sections []*Section
+	var section *Section
+	sectionLines := make(map[string]int)
+	listed := make(map[*Section]int)
+	looseLine, looseEntry := 0, ""
+	for i, raw := range b.readLines(listPath) {
+		lineNo := i + 1
+		line := strings.TrimSpace(raw)
+		if title, ok := strings.CutPrefix(line, "##"); ok {
+			title = strings.TrimSpace(title)
+			id := anchorSlug(strings.ToLower(title))
+			if id == "" {
+				b.diags.Errorf(listPath, lineNo, "invalid section %q, expected '## Title'", line)
+				continue
+			}
+			if first, ok := sectionLines[id]; ok {
+				b.diags.Errorf(listPath, lineNo, "duplicate section %q, first on line %d", title, first)
+				continue
+			}
+			sectionLines[id] = lineNo
+			section = &Section{ID: id, Title: title}
+			sections = append(sections, section)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if section != nil {
+			listed[section]++
+		} else if looseLine == 0 {
+			looseLine, looseEntry = lineNo, line
+		}
+
+		parts := strings.SplitN(line, "|", 2)
+		if len(parts) != 2 {
+			b.diags.Errorf(listPath, lineNo, "invalid line %q, expected 'slug|Title'", line)
+			continue
+		}
+
+		id := strings.TrimSpace(parts[0])
+		title := strings.TrimSpace(parts[1])
+		if id == "" || title == "" {
+			b.diags.Errorf(listPath, lineNo, "invalid line %q, empty slug or title", line)
+			continue
+		}
+		if !slugPat.MatchString(id) {
+			b.diags.Errorf(listPath, lineNo, "invalid slug %q, expected lowercase words joined by dashes", id)
+			continue
+		}
+		if first, ok := seen[id]; ok {
+			b.diags.Errorf(listPath, lineNo, "duplicate slug %q, first listed on line %d", id, first)
+			continue
+		}
+		seen[id] = lineNo
+		if !b.exists(b.exampleDir(id)) {
+			b.diags.Errorf(listPath, lineNo, "no directory %s for %q", filepath.ToSlash(b.exampleDir(id)), id)
+			continue
+		}
+		if !b.isPrimary() && !b.exists("examples/"+id) {
+			b.diags.Errorf(listPath, lineNo, "no directory examples/%s to share the code of %q with", id, id)
+			continue
+		}
+
+		example := &Example{ID: id, Title: title, Name: title, Section: section}
+		if section != nil {
+			section.Examples = append(section.Examples, example)
+		}
+		examples = append(examples, example)
+	}
+
+	var prev *Section
+	for _, section := range sections {
+		if listed[section] == 0 {
+			b.diags.Errorf(listPath, sectionLines[section.ID], "section %q lists no examples", section.Title)
+		}
+		if len(section.Examples) == 0 {
+			continue
+		}
+		if prev != nil {
+			prev.Next = section
+		}
+		prev = section
+	}
+	if len(sections) > 0 && looseLine > 0 {
+		b.diags.Errorf(listPath, looseLine, "%q is listed before the first section, every example must be in one", looseEntry)
+	}
+	b.readMeta(examples)
+
+	// A locale translated with a catalog uses the root examples, and lists
+	// only those it has translated.
+	var dirs []string
+	if b.catalog == nil {
+		dirs = b.glob(b.src("examples/*"))
+	}
+	for _, dir := range dirs {
+		if b.isDir(dir) {
+			if _, ok := seen[filepath.Base(dir)]; !ok {
+				b.diags.Warnf(dir, 0, "example isn't listed in %s", filepath.ToSlash(listPath))
+			}
+		}
+	}
+
+	for i, example := range examples {
+		example.position = i + 1
+		if i > 0 {
+			example.PrevExample = examples[i-1]
+		}
+		if i < (len(examples) - 1) {
+			example.NextExample = examples[i+1]
+		}
+	}
+	return examples
+}
+
+// listSections returns the sections of examples in order, or nil for a
+// list without sections.
+func listSections(examples []*Example) []*Section {
+	var sections []*Section
+	for _, example := range examples {
+		if s := example.Section; s != nil && (len(sections) == 0 || sections[len(sections)-1] != s) {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// parseSelected parses and renders the given examples using the configured
+// number of workers. Each worker fills in only the examples it picks up, so
+// the result doesn't depend on which worker finishes first.
+func (b *builder) parseSelected(examples []*Example) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(b.cfg.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				b.logf("Processing %s [%d/%d]", examples[i].ID, i+1, len(examples))
+				b.parseExample(examples[i])
+			}
+		}()
+	}
+	for i := range examples {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// LoadList reads the examples listed in examples.txt without parsing their
+// sources: each has only its ID and Title set and is linked to its
+// neighbours.
+func LoadList(cfg Config) ([]*Example, error) {
+	b := newBuilder(cfg)
+	examples := b.readExampleList()
+	return examples, b.err()
+}
+
+// Load reads, parses and renders all examples listed in examples.txt,
+// sharing the ones whose code changed with the configured backend.
+func Load(cfg Config) ([]*Example, error) {
+	b := newBuilder(cfg)
+	examples := b.readExampleList()
+	b.parseSelected(examples)
+	return examples, b.err()
+}
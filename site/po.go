@@ -0,0 +1,383 @@
+package site
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// poEntry is a message of a gettext PO catalog. Docs of segments are
+// messages with the context "<example ID>:<segment index>", counting the
+// segments of all of an example's sources in order.
+type poEntry struct {
+	Context, ID, Str string
+
+	// Refs are the source positions from "#:" comments.
+	Refs []string
+
+	// Fuzzy entries need their translation checked, as their source changed;
+	// PrevID is the source the translation was made for.
+	Fuzzy  bool
+	PrevID string
+
+	// Line is where the entry starts in the catalog.
+	Line int
+
+	// Obsolete entries are written commented out with "#~"; they keep the
+	// translations of segments that are gone, in case they come back.
+	Obsolete bool
+}
+
+// catalog is a parsed PO file.
+type catalog struct {
+	path     string
+	header   string
+	entries  map[string]*poEntry
+	obsolete map[string]*poEntry
+}
+
+func newCatalog(path string) *catalog {
+	return &catalog{path: path, entries: make(map[string]*poEntry), obsolete: make(map[string]*poEntry)}
+}
+
+// CatalogStats counts the entries of a PO catalog by state.
+type CatalogStats struct {
+	Entries      int
+	New          int
+	Fuzzy        int
+	Untranslated int
+	Obsolete     int
+}
+
+func segContext(id string, index int) string {
+	return fmt.Sprintf("%s:%d", id, index)
+}
+
+// readCatalog parses the PO file at path, or returns nil after recording a
+// diagnostic if it can't be read or parsed. A missing file is an empty
+// catalog when allowMissing is set. A context used by two entries is
+// reported, and the first of them kept.
+func (b *builder) readCatalog(path string, allowMissing bool) *catalog {
+	cat := newCatalog(path)
+	data, err := os.ReadFile(b.path(path))
+	if allowMissing && errors.Is(err, fs.ErrNotExist) {
+		return cat
+	}
+	if b.failed(path, err) {
+		return nil
+	}
+
+	var (
+		entry *poEntry
+		field *string
+	)
+	flush := func() {
+		if entry == nil {
+			return
+		}
+		entries := cat.entries
+		if entry.Obsolete {
+			entries = cat.obsolete
+		}
+		switch first := entries[entry.Context]; {
+		case entry.ID == "" && entry.Context == "" && !entry.Obsolete:
+			cat.header = entry.Str
+		case first != nil:
+			b.diags.Errorf(path, entry.Line, "duplicate msgctxt %q, first used at line %d", entry.Context, first.Line)
+		default:
+			entries[entry.Context] = entry
+		}
+		entry, field = nil, nil
+	}
+	start := func(lineNo int, obsolete bool) {
+		if entry == nil {
+			entry = &poEntry{Line: lineNo, Obsolete: obsolete}
+		}
+	}
+	for i, raw := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		line := strings.TrimSpace(raw)
+		// The lines of obsolete entries are those of other entries behind
+		// "#~", and their previous msgid is behind "#~|".
+		rest, obsolete := strings.CutPrefix(line, "#~")
+		if obsolete {
+			line = strings.TrimSpace(rest)
+			if strings.HasPrefix(line, "|") {
+				line = "#" + line
+			}
+			// The comments before them belong to them too.
+			if entry != nil && !entry.Obsolete {
+				if field != nil {
+					flush()
+				} else {
+					entry.Obsolete = true
+				}
+			}
+		} else if entry != nil && entry.Obsolete && line != "" {
+			flush()
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		switch {
+		case line == "" && !obsolete:
+			flush()
+		case line == "":
+		case keyword == "#:":
+			start(lineNo, obsolete)
+			entry.Refs = append(entry.Refs, strings.Fields(rest)...)
+		case keyword == "#,":
+			start(lineNo, obsolete)
+			for _, flag := range strings.Split(rest, ",") {
+				if strings.TrimSpace(flag) == "fuzzy" {
+					entry.Fuzzy = true
+				}
+			}
+		case keyword == "#|":
+			start(lineNo, obsolete)
+			quoted, isID := strings.CutPrefix(rest, "msgid ")
+			if isID || strings.HasPrefix(quoted, `"`) {
+				s, err := strconv.Unquote(quoted)
+				if err != nil {
+					b.diags.Errorf(path, lineNo, "invalid string %s", quoted)
+				}
+				entry.PrevID += s
+			}
+		case strings.HasPrefix(line, "#"):
+		case keyword == "msgctxt" || keyword == "msgid" || keyword == "msgstr":
+			if entry != nil && field == &entry.Str && keyword != "msgstr" {
+				flush()
+			}
+			start(lineNo, obsolete)
+			switch keyword {
+			case "msgctxt":
+				field = &entry.Context
+			case "msgid":
+				field = &entry.ID
+			default:
+				field = &entry.Str
+			}
+			s, err := strconv.Unquote(rest)
+			if err != nil {
+				b.diags.Errorf(path, lineNo, "invalid string %s", rest)
+			}
+			*field = s
+		case strings.HasPrefix(line, `"`) && field != nil:
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				b.diags.Errorf(path, lineNo, "invalid string %s", line)
+			}
+			*field += s
+		default:
+			b.diags.Errorf(path, lineNo, "unexpected line %q", line)
+		}
+	}
+	flush()
+	return cat
+}
+
+// poString formats s as a PO string, split after each newline.
+func poString(keyword, s string) string {
+	if !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") {
+		return keyword + " " + strconv.Quote(s) + "\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(keyword + " \"\"\n")
+	for _, part := range strings.SplitAfter(s, "\n") {
+		if part != "" {
+			sb.WriteString(strconv.Quote(part) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// writeCatalog writes the entries, in the given order, after the header,
+// and the obsolete entries after them by context.
+func (b *builder) writeCatalog(cat *catalog, order []string) {
+	var sb strings.Builder
+	sb.WriteString(poString("msgid", ""))
+	sb.WriteString(poString("msgstr", cat.header))
+	for _, ctx := range order {
+		sb.WriteString("\n")
+		writeEntry(&sb, cat.entries[ctx])
+	}
+	obsolete := make([]string, 0, len(cat.obsolete))
+	for ctx := range cat.obsolete {
+		obsolete = append(obsolete, ctx)
+	}
+	sort.Strings(obsolete)
+	for _, ctx := range obsolete {
+		sb.WriteString("\n")
+		writeEntry(&sb, cat.obsolete[ctx])
+	}
+	path := b.path(cat.path)
+	if b.failed(cat.path, os.MkdirAll(filepath.Dir(path), 0755)) {
+		return
+	}
+	b.failed(cat.path, os.WriteFile(path, []byte(sb.String()), 0644))
+}
+
+// writeEntry writes an entry, with its strings behind "#~ " and its
+// previous msgid behind "#~| " when it's obsolete.
+func writeEntry(sb *strings.Builder, entry *poEntry) {
+	prefix, prevPrefix := "", "#| "
+	if entry.Obsolete {
+		prefix, prevPrefix = "#~ ", "#~| "
+	}
+	lines := func(prefix, s string) {
+		for _, line := range strings.SplitAfter(s, "\n") {
+			if line != "" {
+				sb.WriteString(prefix + line)
+			}
+		}
+	}
+	if len(entry.Refs) > 0 {
+		sb.WriteString("#: " + strings.Join(entry.Refs, " ") + "\n")
+	}
+	if entry.Fuzzy {
+		sb.WriteString("#, fuzzy\n")
+		if entry.PrevID != "" {
+			lines(prevPrefix, poString("msgid", entry.PrevID))
+		}
+	}
+	lines(prefix, poString("msgctxt", entry.Context))
+	lines(prefix, poString("msgid", entry.ID))
+	lines(prefix, poString("msgstr", entry.Str))
+}
+
+// sourcePaths returns the .go and .sh sources of an example in the order
+// their segments are rendered.
+func (b *builder) sourcePaths(dir string) []string {
+	var paths []string
+	for _, path := range b.glob(dir + "/*") {
+		if ext := filepath.Ext(path); ext == ".go" || ext == ".sh" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Extract writes the doc comments of all examples listed in examples.txt
+// into the PO catalog at catalogPath, relative to the root, one entry per
+// segment with docs. An existing catalog is merged: translations are kept,
+// and entries whose source changed are marked fuzzy. The translated
+// entries of segments that are gone are kept as obsolete ones, which are
+// used again if their segments come back.
+func Extract(cfg Config, catalogPath string) (*CatalogStats, error) {
+	b := newBuilder(cfg)
+	old := b.readCatalog(catalogPath, true)
+	if old == nil {
+		return nil, b.err()
+	}
+	cat := newCatalog(catalogPath)
+	cat.header = old.header
+	if cat.header == "" {
+		cat.header = "Content-Type: text/plain; charset=UTF-8\n"
+	}
+
+	stats := &CatalogStats{}
+	var order []string
+	for _, example := range b.readExampleList() {
+		index := 0
+		for _, path := range b.sourcePaths("examples/" + example.ID) {
+			segs, _ := b.parseSegs(path)
+			spans := segmentLines(b.readLines(path))
+			for i, seg := range segs {
+				ctx := segContext(example.ID, index+i)
+				if seg.Docs == "" {
+					continue
+				}
+				entry := &poEntry{
+					Context: ctx,
+					ID:      seg.Docs,
+					Refs:    []string{fmt.Sprintf("%s:%d", filepath.ToSlash(path), spans[i].docs[0]+1)},
+				}
+				prev := old.entries[ctx]
+				if prev == nil {
+					prev = old.obsolete[ctx]
+				}
+				if prev == nil {
+					stats.New++
+				} else {
+					entry.Str = prev.Str
+					entry.Fuzzy = prev.Fuzzy
+					entry.PrevID = prev.PrevID
+					if prev.ID != seg.Docs && prev.Str != "" {
+						entry.Fuzzy = true
+						if entry.PrevID == "" {
+							entry.PrevID = prev.ID
+						}
+					}
+				}
+				cat.entries[ctx] = entry
+				order = append(order, ctx)
+			}
+			index += len(segs)
+		}
+	}
+	if b.diags.Errors() > 0 {
+		return nil, b.err()
+	}
+
+	for _, entries := range []map[string]*poEntry{old.entries, old.obsolete} {
+		for ctx, entry := range entries {
+			if cat.entries[ctx] == nil && cat.obsolete[ctx] == nil && entry.Str != "" {
+				entry.Obsolete = true
+				entry.Refs = nil
+				cat.obsolete[ctx] = entry
+				stats.Obsolete++
+			}
+		}
+	}
+	for _, entry := range cat.entries {
+		stats.Entries++
+		switch {
+		case entry.Str == "":
+			stats.Untranslated++
+		case entry.Fuzzy:
+			stats.Fuzzy++
+		}
+	}
+	b.writeCatalog(cat, order)
+	return stats, b.err()
+}
+
+// localizeSegs replaces the docs of an example's segments, the first of
+// which has the given index, with their translations from the locale's
+// catalog. Segments without a translation, or with a fuzzy one, keep their
+// original docs and are reported.
+func (b *builder) localizeSegs(id string, first int, segs []*Seg) {
+	for i, seg := range segs {
+		if seg.Docs == "" {
+			continue
+		}
+		ctx := segContext(id, first+i)
+		entry := b.catalog.entries[ctx]
+		switch {
+		case entry == nil || entry.Str == "":
+			b.diags.Warnf(b.catalog.path, 0, "%s: untranslated", ctx)
+		case entry.Fuzzy:
+			b.diags.Warnf(b.catalog.path, entry.Line, "%s: fuzzy translation, using the original", ctx)
+		case entry.ID != seg.Docs:
+			b.diags.Warnf(b.catalog.path, entry.Line, "%s: source changed, rerun tools/extract", ctx)
+		default:
+			seg.Docs = entry.Str
+		}
+	}
+}
+
+// exampleKey hashes the catalog's entries for an example, for the key of
+// the example's page.
+func (c *catalog) exampleKey(id string) string {
+	var parts []string
+	for ctx, entry := range c.entries {
+		if strings.HasPrefix(ctx, id+":") {
+			parts = append(parts, fmt.Sprintf("%s\x00%s\x00%s\x00%t", ctx, entry.ID, entry.Str, entry.Fuzzy))
+		}
+	}
+	sort.Strings(parts)
+	return sha1Sum(strings.Join(parts, "\n"))
+}
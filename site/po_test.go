@@ -0,0 +1,115 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	const catalogPath = "po/en.po"
+	root := t.TempDir()
+	source := func(first, second string) {
+		t.Helper()
+		code := "package main\n\nimport \"fmt\"\n\n// " + second + "\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+		if first != "" {
+			code = "// " + first + "\n" + code
+		}
+		writeFiles(t, root, "examples.txt", "hello|Hello\n", "examples/hello/hello.go", code)
+	}
+	extract := func(want CatalogStats) *catalog {
+		t.Helper()
+		diags := &Diagnostics{}
+		stats, err := Extract(Config{Root: root, Diagnostics: diags, Settings: DefaultSettings()}, catalogPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *stats != want {
+			t.Errorf("stats = %+v, want %+v", *stats, want)
+		}
+		checkDiags(t, diags)
+		cat := newBuilder(Config{Root: root, Diagnostics: diags, Settings: DefaultSettings()}).readCatalog(catalogPath, false)
+		checkDiags(t, diags)
+		return cat
+	}
+
+	source("Greet.", "Print.")
+	cat := extract(CatalogStats{Entries: 2, New: 2, Untranslated: 2})
+	if e := cat.entries["hello:0"]; e == nil || e.ID != "Greet." || len(e.Refs) != 1 || e.Refs[0] != "examples/hello/hello.go:1" {
+		t.Fatalf("hello:0 is %+v", e)
+	}
+	if e := cat.entries["hello:2"]; e == nil || e.ID != "Print." {
+		t.Fatalf("hello:2 is %+v", e)
+	}
+
+	// Translate both, then change the docs of one and remove the other.
+	cat.entries["hello:0"].Str = "Здороваемся."
+	cat.entries["hello:2"].Str = "Печатаем."
+	b := newBuilder(Config{Root: root, Settings: DefaultSettings()})
+	b.writeCatalog(cat, []string{"hello:0", "hello:2"})
+	source("", "Print twice.")
+	cat = extract(CatalogStats{Entries: 1, Fuzzy: 1, Obsolete: 1})
+	if e := cat.entries["hello:2"]; e == nil || !e.Fuzzy || e.ID != "Print twice." || e.PrevID != "Print." || e.Str != "Печатаем." {
+		t.Errorf("changed hello:2 is %+v, want fuzzy with the old source and translation", e)
+	}
+	if e := cat.obsolete["hello:0"]; e == nil || e.Str != "Здороваемся." || cat.entries["hello:0"] != nil {
+		t.Errorf("removed hello:0 is %+v, want obsolete with its translation", e)
+	}
+	data, err := os.ReadFile(filepath.Join(root, catalogPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"#, fuzzy\n#| msgid \"Print.\"\nmsgctxt \"hello:2\"\n", "\n#~ msgctxt \"hello:0\"\n#~ msgid \"Greet.\"\n#~ msgstr \"Здороваемся.\"\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("catalog has no\n%s\nin\n%s", want, data)
+		}
+	}
+
+	// Extracting again changes nothing, and the removed segment's
+	// translation is used again when it comes back.
+	extract(CatalogStats{Entries: 1, Fuzzy: 1, Obsolete: 1})
+	source("Greet.", "Print twice.")
+	cat = extract(CatalogStats{Entries: 2, Fuzzy: 1})
+	if e := cat.entries["hello:0"]; e == nil || e.Fuzzy || e.Str != "Здороваемся." || len(cat.obsolete) != 0 {
+		t.Errorf("restored hello:0 is %+v, want its translation back", e)
+	}
+}
+
+func TestReadCatalog(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "en.po", `msgid ""
+msgstr "Content-Type: text/plain; charset=UTF-8\n"
+
+#: examples/hello/hello.go:1
+#, fuzzy
+#| msgid "Hi.\n"
+msgctxt "hello:0"
+msgid ""
+"Hello\n"
+"there.\n"
+msgstr "Привет.\n"
+
+msgctxt "hello:0"
+msgid "Again.\n"
+msgstr "Снова.\n"
+
+#, fuzzy
+#~| msgid "Old.\n"
+#~ msgctxt "hello:3"
+#~ msgid "Gone.\n"
+#~ msgstr "Нет.\n"
+`)
+	diags := &Diagnostics{}
+	cat := newBuilder(Config{Root: root, Diagnostics: diags, Settings: DefaultSettings()}).readCatalog("en.po", false)
+	checkDiags(t, diags, `en.po:13: error: duplicate msgctxt "hello:0", first used at line 4`)
+	if e := cat.entries["hello:0"]; e == nil || e.ID != "Hello\nthere.\n" || e.Str != "Привет.\n" || !e.Fuzzy || e.PrevID != "Hi.\n" || e.Line != 4 {
+		t.Errorf("hello:0 is %+v", e)
+	}
+	if e := cat.obsolete["hello:3"]; e == nil || e.ID != "Gone.\n" || e.Str != "Нет.\n" || !e.Fuzzy || e.PrevID != "Old.\n" || cat.entries["hello:3"] != nil {
+		t.Errorf("obsolete hello:3 is %+v", e)
+	}
+	if cat.header != "Content-Type: text/plain; charset=UTF-8\n" {
+		t.Errorf("header is %q", cat.header)
+	}
+}
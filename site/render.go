@@ -0,0 +1,156 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// templatePosPat matches the template name and line at the start of errors
+// from text/template.
+var templatePosPat = regexp.MustCompile(`^template: ([^:]+):(\d+)`)
+
+// templateFailed records a template error, if there is one, at the line the
+// error names. Parse errors belong to path; execution errors name the
+// template they happened in, which is the file with the same name in the
+// locale's or the root templates/.
+func (b *builder) templateFailed(path string, err error, executing bool) bool {
+	if err == nil {
+		return false
+	}
+	line := 0
+	if m := templatePosPat.FindStringSubmatch(err.Error()); m != nil {
+		if executing {
+			path = b.templatePath(m[1] + ".tmpl")
+		}
+		line, _ = strconv.Atoi(m[2])
+	}
+	b.diags.Errorf(path, line, "%v", err)
+	return true
+}
+
+// parseTemplates parses the given files from templates/, or the locale's
+// copies of them, into one template, or returns nil after recording a
+// diagnostic for a file that doesn't parse.
+func (b *builder) parseTemplates(name string, files ...string) *template.Template {
+	tmpl := template.New(name)
+	for _, file := range files {
+		path := b.templatePath(file)
+		if _, err := tmpl.Parse(b.readFile(path)); b.templateFailed(path, err, false) {
+			return nil
+		}
+	}
+	return tmpl
+}
+
+// renderPage executes tmpl with data into the file name in outDir.
+func (b *builder) renderPage(tmpl *template.Template, tmplPath, outDir, name string, data any) {
+	path := filepath.Join(outDir, name)
+	f, err := os.Create(path)
+	if b.failed(path, err) {
+		return
+	}
+	defer f.Close()
+	b.templateFailed(tmplPath, tmpl.Execute(f, data), true)
+}
+
+func (b *builder) renderIndex(examples []*Example, alts []Alternate, site *SiteConfig, outDir string) {
+	b.logf("Rendering index")
+	indexTmpl := b.parseTemplates("index", "footer.tmpl", "locales.tmpl", "index.tmpl")
+	if indexTmpl == nil {
+		return
+	}
+	data := IndexData{
+		Examples:   examples,
+		Sections:   listSections(examples),
+		Alternates: alts,
+		Site:       site,
+		URL:        b.pageURL(""),
+	}
+	b.renderPage(indexTmpl, b.templatePath("index.tmpl"), outDir, "index.html", data)
+}
+
+func (b *builder) renderExamples(examples []*Example, site *SiteConfig, outDir string) {
+	b.logf("Rendering examples")
+	exampleTmpl := b.parseTemplates("example", "footer.tmpl", "locales.tmpl", "example.tmpl")
+	if exampleTmpl == nil {
+		return
+	}
+	for _, example := range examples {
+		example.Site = site
+		example.URL = b.pageURL(example.ID)
+		example.JSONLD = b.articleJSONLD(example, example.position, site)
+		b.renderPage(exampleTmpl, b.templatePath("example.tmpl"), outDir, example.ID, example)
+	}
+}
+
+func (b *builder) render404(site *SiteConfig, outDir string) {
+	b.logf("Rendering 404")
+	tmpl := b.parseTemplates("404", "footer.tmpl", "404.tmpl")
+	if tmpl == nil {
+		return
+	}
+	data := NotFoundData{Site: site}
+	b.renderPage(tmpl, b.templatePath("404.tmpl"), outDir, "404.html", data)
+}
+
+// renderChangelog renders the changelog page and the feed of changes.
+func (b *builder) renderChangelog(changes []*Change, site *SiteConfig, outDir string) {
+	b.logf("Rendering changelog")
+	data := ChangelogData{Changes: changes, Site: site, Base: b.pageURL(""), FeedID: b.pageURL(FeedFile)}
+	if data.FeedID == "" {
+		data.FeedID = "urn:uuid:" + nameUUID(site.Title+":"+site.Language)
+	}
+	if len(changes) > 0 {
+		data.Updated = changes[0].Time
+	} else {
+		data.Updated = time.Now().UTC().Format(time.RFC3339)
+	}
+	if tmpl := b.parseTemplates("changelog", "footer.tmpl", "changelog.tmpl"); tmpl != nil {
+		b.renderPage(tmpl, b.templatePath("changelog.tmpl"), outDir, ChangelogFile, data)
+	}
+	if tmpl := b.parseTemplates("feed", "feed.tmpl"); tmpl != nil {
+		b.renderPage(tmpl, b.templatePath("feed.tmpl"), outDir, FeedFile, data)
+	}
+}
+
+func (b *builder) copyAsset(name, outDir string) {
+	dat, err := os.ReadFile(b.path("templates/" + name))
+	if b.failed("templates/"+name, err) {
+		return
+	}
+	dst := filepath.Join(outDir, name)
+	b.failed(dst, os.WriteFile(dst, dat, 0644))
+}
+
+// Render writes the complete site for examples, as returned by Load, into
+// outDir: the assets and chroma.css, the index, a page per example, the
+// 404 page, the search index, the API index, robots.txt and the sitemap, and with
+// Config.History the changelog and the feed. Only the primary locale is
+// rendered; Build renders the others as well.
+func Render(cfg Config, examples []*Example, outDir string) error {
+	b := newBuilder(cfg)
+	if b.failed(outDir, os.MkdirAll(outDir, 0755)) {
+		return b.err()
+	}
+	for _, name := range b.settings.Assets {
+		b.copyAsset(name, outDir)
+	}
+	m, old := &Manifest{Pages: make(map[string]string)}, &Manifest{}
+	b.writeGenerated(m, old, outDir, ChromaCSSFile, b.chromaCSS())
+	site := b.siteConfig()
+	b.renderIndex(examples, nil, site, outDir)
+	b.renderExamples(examples, site, outDir)
+	b.render404(site, outDir)
+	b.writeSearchIndex(examples, outDir)
+	b.renderAPIIndex(examples, site, outDir)
+	if changes, ok := b.readHistory(examples); ok {
+		b.renderChangelog(changes, site, outDir)
+	}
+	b.writeGenerated(m, old, outDir, SitemapFile, b.sitemap(examples, nil))
+	b.writeGenerated(m, old, outDir, RobotsFile, b.robots())
+	return b.err()
+}
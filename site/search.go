@@ -0,0 +1,169 @@
+package site
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SearchFile is the name of the search index written next to the index page
+// of every locale, which templates/search.js queries.
+const SearchFile = "search.json"
+
+// searchExample is an example in the search index. The index is kept
+// compact: segments only carry their plain doc text and the identifiers
+// used in their code, and the short keys save a few more bytes.
+type searchExample struct {
+	ID    string      `json:"id"`
+	Title string      `json:"t"`
+	Segs  []searchSeg `json:"s"`
+}
+
+type searchSeg struct {
+	Anchor string   `json:"a"`
+	Text   string   `json:"d,omitempty"`
+	Idents []string `json:"i,omitempty"`
+}
+
+var (
+	mdLinkPat  = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdMarksPat = regexp.MustCompile("[`*_]+")
+	htmlTagPat = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// docText turns the markdown docs of a segment into plain text.
+func docText(docs string) string {
+	text := mdLinkPat.ReplaceAllString(docs, "$1")
+	text = htmlTagPat.ReplaceAllString(text, "")
+	text = mdMarksPat.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// importNames maps the names that Go sources refer to imported packages by
+// to the packages' own names, for imports such as `s "strings"`.
+func (b *builder) importNames(paths []string) map[string]string {
+	names := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if filepath.Ext(path) != ".go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, b.path(path), nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if imp.Name != nil {
+				pkg := strings.Trim(imp.Path.Value, `"`)
+				names[imp.Name.Name] = pkg[strings.LastIndex(pkg, "/")+1:]
+			}
+		}
+	}
+	return names
+}
+
+// codeIdents returns the identifiers in a fragment of Go code in the order
+// they first appear, with qualified ones such as time.Tick kept together.
+// Qualifiers found in imports are replaced by the package name.
+func codeIdents(code string, imports map[string]string) []string {
+	var s scanner.Scanner
+	src := []byte(code)
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", -1, len(src)), src, nil, 0)
+
+	var idents []string
+	seen := make(map[string]bool)
+	add := func(ident string) {
+		if len(ident) > 1 && !seen[ident] {
+			seen[ident] = true
+			idents = append(idents, ident)
+		}
+	}
+	prev, prevLit := token.ILLEGAL, ""
+	dotted := false
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch {
+		case tok == token.IDENT && dotted && prev == token.PERIOD:
+			// Replace the qualifier with the qualified identifier.
+			if n := len(idents); n > 0 && idents[n-1] == prevLit {
+				idents = idents[:n-1]
+				delete(seen, prevLit)
+			}
+			qualifier := prevLit
+			if pkg, ok := imports[qualifier]; ok {
+				qualifier = pkg
+			}
+			add(qualifier + "." + lit)
+		case tok == token.IDENT:
+			add(lit)
+		}
+		if tok == token.IDENT {
+			prevLit = lit
+			dotted = false
+		}
+		if tok == token.PERIOD && prev == token.IDENT {
+			dotted = true
+		}
+		prev = tok
+	}
+	return idents
+}
+
+// readSegs parses the sources of an example into segments without
+// rendering them, for examples that weren't parsed in this build.
+func (b *builder) readSegs(example *Example) [][]*Seg {
+	var all [][]*Seg
+	n := 0
+	for _, path := range b.sourcePaths(b.exampleDir(example.ID)) {
+		segs, _ := b.parseSegs(path)
+		if b.catalog != nil {
+			b.localizeSegs(example.ID, n, segs)
+		}
+		n += len(segs)
+		all = append(all, segs)
+	}
+	setAnchors(example.ID, all)
+	return all
+}
+
+// writeSearchIndex writes the search index of examples into outDir. The
+// segments of examples that weren't parsed are read again.
+func (b *builder) writeSearchIndex(examples []*Example, outDir string) {
+	b.logf("Writing search index")
+	index := make([]searchExample, 0, len(examples))
+	for _, example := range examples {
+		segs := example.Segs
+		if segs == nil {
+			segs = b.readSegs(example)
+		}
+		entry := searchExample{ID: example.ID, Title: example.Title}
+		imports := b.importNames(b.sourcePaths(b.exampleDir(example.ID)))
+		for _, sourceSegs := range segs {
+			for _, seg := range sourceSegs {
+				s := searchSeg{Anchor: seg.Anchor, Text: docText(seg.Docs)}
+				if seg.goCode {
+					s.Idents = codeIdents(seg.Code, imports)
+				}
+				if s.Text != "" || len(s.Idents) > 0 {
+					entry.Segs = append(entry.Segs, s)
+				}
+			}
+		}
+		index = append(index, entry)
+	}
+	data, err := json.Marshal(index)
+	if b.failed(SearchFile, err) {
+		return
+	}
+	path := filepath.Join(outDir, SearchFile)
+	b.failed(path, os.WriteFile(path, append(data, '\n'), 0644))
+}
@@ -0,0 +1,223 @@
+package site
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SitemapFile and RobotsFile are written into the output directory when
+// the site has a base URL: a sitemap per locale, and robots.txt pointing
+// to them.
+const (
+	SitemapFile = "sitemap.xml"
+	RobotsFile  = "robots.txt"
+)
+
+// descriptionLen is the length in runes that page descriptions are cut to,
+// about what search results and link previews show.
+const descriptionLen = 200
+
+// pageURL returns the absolute URL of a page of the builder's locale, an
+// example ID or "" for the index, or "" if the site has no base URL.
+func (b *builder) pageURL(page string) string {
+	if b.settings.BaseURL == "" {
+		return ""
+	}
+	return alternateHref(b.settings.BaseURL, b.locale, b.locale, page)
+}
+
+// description returns the plain text of the first docs of an example, cut
+// at a word boundary, for the description and OpenGraph meta tags.
+func description(segs [][]*Seg) string {
+	for _, sourceSegs := range segs {
+		for _, seg := range sourceSegs {
+			text := docText(seg.Docs)
+			if text == "" {
+				continue
+			}
+			if utf8.RuneCountInString(text) <= descriptionLen {
+				return text
+			}
+			cut := string([]rune(text)[:descriptionLen])
+			if i := strings.LastIndex(cut, " "); i > 0 {
+				cut = cut[:i]
+			}
+			return strings.TrimRight(cut, ",.:;—- ") + "…"
+		}
+	}
+	return ""
+}
+
+// articleItem is a TechArticle in a series, for the JSON-LD data of an
+// example page. It is also a ListItem, which is what links it to its
+// neighbours.
+type articleItem struct {
+	Context      string          `json:"@context,omitempty"`
+	Type         []string        `json:"@type"`
+	Headline     string          `json:"headline"`
+	URL          string          `json:"url,omitempty"`
+	Position     int             `json:"position,omitempty"`
+	Section      string          `json:"articleSection,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	InLanguage   string          `json:"inLanguage,omitempty"`
+	DateModified string          `json:"dateModified,omitempty"`
+	Author       []articlePerson `json:"author,omitempty"`
+	IsPartOf     *articleSeries  `json:"isPartOf,omitempty"`
+	PreviousItem *articleItem    `json:"previousItem,omitempty"`
+	NextItem     *articleItem    `json:"nextItem,omitempty"`
+}
+
+type articlePerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type articleSeries struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+var articleType = []string{"TechArticle", "ListItem"}
+
+// articleJSONLD returns the JSON-LD data of an example page: a TechArticle
+// with its position among the examples and its previous and next ones. The
+// URLs are left out without a base URL, as they must be absolute.
+func (b *builder) articleJSONLD(example *Example, position int, site *SiteConfig) string {
+	index := b.pageURL("")
+	item := articleItem{
+		Context:      "https://schema.org",
+		Type:         articleType,
+		Headline:     example.Title,
+		URL:          b.pageURL(example.ID),
+		Position:     position,
+		Description:  example.Description,
+		InLanguage:   site.Language,
+		DateModified: example.Modified,
+		IsPartOf:     &articleSeries{Type: "CreativeWorkSeries", Name: site.Title, URL: index},
+	}
+	if example.Section != nil {
+		item.Section = example.Section.Title
+	}
+	for _, author := range site.Authors {
+		item.Author = append(item.Author, articlePerson{Type: "Person", Name: author.Name, URL: author.URL})
+	}
+	if prev := example.PrevExample; prev != nil {
+		item.PreviousItem = &articleItem{Type: articleType, Headline: prev.Title, URL: b.pageURL(prev.ID)}
+	}
+	if next := example.NextExample; next != nil {
+		item.NextItem = &articleItem{Type: articleType, Headline: next.Title, URL: b.pageURL(next.ID)}
+	}
+	// json.Marshal escapes <, > and &, so the data can't end the script
+	// element it's embedded in.
+	data, err := json.Marshal(item)
+	if b.failed(example.ID, err) {
+		return ""
+	}
+	return string(data)
+}
+
+// modified returns the date an example's sources last changed: the one
+// recorded in the previous manifest while they stay the same, and today's
+// date once they change.
+func modified(prev *ManifestExample, sources map[string]string) string {
+	if prev != nil && prev.Modified != "" && inputsKey(prev.Sources) == inputsKey(sources) {
+		return prev.Modified
+	}
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	XHTML   string       `xml:"xmlns:xhtml,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string        `xml:"loc"`
+	LastMod    string        `xml:"lastmod,omitempty"`
+	Alternates []sitemapLink `xml:"xhtml:link"`
+}
+
+type sitemapLink struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+func sitemapLinks(alts []Alternate) []sitemapLink {
+	var links []sitemapLink
+	for _, alt := range alts {
+		links = append(links, sitemapLink{Rel: "alternate", Hreflang: alt.Language, Href: alt.Href})
+	}
+	return links
+}
+
+// sitemap returns the sitemap of the locale's pages, or "" without a base
+// URL. The index was last modified when the latest example was.
+func (b *builder) sitemap(examples []*Example, indexAlts []Alternate) string {
+	if b.settings.BaseURL == "" {
+		return ""
+	}
+	set := sitemapURLSet{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		XHTML: "http://www.w3.org/1999/xhtml",
+	}
+	latest := ""
+	for _, example := range examples {
+		latest = max(latest, example.Modified)
+	}
+	set.URLs = append(set.URLs, sitemapURL{Loc: b.pageURL(""), LastMod: latest, Alternates: sitemapLinks(indexAlts)})
+	for _, example := range examples {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:        b.pageURL(example.ID),
+			LastMod:    example.Modified,
+			Alternates: sitemapLinks(example.Alternates),
+		})
+	}
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if b.failed(SitemapFile, err) {
+		return ""
+	}
+	return xml.Header + string(data) + "\n"
+}
+
+// robots returns robots.txt for the site, which points crawlers to the
+// sitemaps of all locales when there is a base URL.
+func (b *builder) robots() string {
+	var sb strings.Builder
+	sb.WriteString("User-agent: *\nAllow: /\n")
+	if b.settings.BaseURL != "" {
+		sb.WriteString("\n")
+		locales := append([]Locale{*b.settings.primaryLocale()}, b.settings.Locales...)
+		for _, loc := range locales {
+			index := alternateHref(b.settings.BaseURL, &loc, &loc, "")
+			fmt.Fprintf(&sb, "Sitemap: %s%s\n", index, SitemapFile)
+		}
+	}
+	return sb.String()
+}
+
+// writeGenerated writes content into the file name in outDir unless the
+// previous build wrote the same, recording its hash in the manifest. Empty
+// content removes the file.
+func (b *builder) writeGenerated(m, old *Manifest, outDir, name, content string) {
+	path := filepath.Join(outDir, name)
+	if content == "" {
+		os.Remove(path)
+		return
+	}
+	m.Pages[name] = sha1Sum(content)
+	if upToDate(outDir, name, old.Pages[name], m.Pages[name]) {
+		return
+	}
+	b.failed(path, os.WriteFile(path, []byte(content), 0644))
+}
@@ -0,0 +1,40 @@
+package site
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArticleJSONLD(t *testing.T) {
+	hello := &Example{ID: "hello", Title: "Hello"}
+	values := &Example{ID: "values", Title: "Values", PrevExample: hello}
+	hello.NextExample = values
+	tests := []struct {
+		baseURL string
+		want    []string
+		notWant []string
+	}{
+		{"https://example.com/go/", []string{
+			`"url":"https://example.com/go/hello"`,
+			`"url":"https://example.com/go/"`,
+			`"nextItem":{"@type":["TechArticle","ListItem"],"headline":"Values","url":"https://example.com/go/values"}`,
+		}, nil},
+		{"", []string{`"nextItem":{"@type":["TechArticle","ListItem"],"headline":"Values"}`}, []string{`"url"`}},
+	}
+	for _, tt := range tests {
+		settings := DefaultSettings()
+		settings.BaseURL = tt.baseURL
+		b := newBuilder(Config{Root: t.TempDir(), Settings: settings})
+		got := b.articleJSONLD(hello, 1, &SiteConfig{Title: "Go", Language: "ru"})
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("base URL %q: no %s in\n%s", tt.baseURL, want, got)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("base URL %q: %s in\n%s", tt.baseURL, notWant, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package site
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// SettingsFile is the name of the site configuration file in the root.
+const SettingsFile = "site.json"
+
+// Settings is the declarative configuration of a site, read from site.json.
+// Forks and translations change it instead of patching templates and code.
+type Settings struct {
+	// Title is the name of the site, used in page titles and headers.
+	Title string `json:"title"`
+
+	// BaseURL is the absolute URL the site is served from, e.g.
+	// "https://gobyexample.com". It may be empty while nothing needs
+	// absolute links.
+	BaseURL string `json:"baseURL"`
+
+	// Language is the BCP 47 tag of the site's language, e.g. "ru".
+	Language string `json:"language"`
+
+	// LanguageName is the name of Language in the language switcher, e.g.
+	// "Русский". Only needed with Locales.
+	LanguageName string `json:"languageName"`
+
+	// Locales are further translations built from the same code, each
+	// into its own directory of the site. See Locale.
+	Locales []Locale `json:"locales"`
+
+	// Style is the name of the chroma style used for highlighting code,
+	// and DarkStyle the one used instead when the reader prefers a dark
+	// color scheme; empty means Style for both. Their CSS is generated into
+	// chroma.css.
+	Style     string `json:"style"`
+	DarkStyle string `json:"darkStyle"`
+
+	// Assets are the files copied from templates/ into the site as they
+	// are.
+	Assets []string `json:"assets"`
+
+	// Playground is the base URL of the Go Playground that examples are
+	// shared with: https://go.dev or a self-hosted instance.
+	Playground string `json:"playground"`
+
+	// OutDir is the directory the site is generated into and served and
+	// uploaded from.
+	OutDir string `json:"outDir"`
+
+	// Authors are credited in the footer of every page.
+	Authors []Author `json:"authors"`
+
+	// SourceURL and LicenseURL are linked from the footer.
+	SourceURL  string `json:"sourceURL"`
+	LicenseURL string `json:"licenseURL"`
+}
+
+// Author is a person credited in the footer.
+type Author struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// GoDevPlayground is the Playground setting for the public playground.
+const GoDevPlayground = "https://go.dev"
+
+// DefaultSettings returns the settings used for a root without site.json.
+func DefaultSettings() *Settings {
+	return &Settings{
+		Title:      "Go by Example",
+		Language:   "en",
+		Style:      "gobyexample",
+		DarkStyle:  "gobyexample-dark",
+		Assets:     []string{"site.css", "site.js", "search.js", "favicon.ico", "play.png", "clipboard.png"},
+		Playground: GoDevPlayground,
+		OutDir:     "public",
+	}
+}
+
+var languagePat = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// LoadSettings reads site.json from root, filling in defaults for the
+// fields it leaves out. Without site.json it returns DefaultSettings.
+func LoadSettings(root string) (*Settings, error) {
+	b := newBuilder(Config{Root: root, Settings: DefaultSettings()})
+	settings := b.loadSettings()
+	return settings, b.err()
+}
+
+func (b *builder) loadSettings() *Settings {
+	settings := DefaultSettings()
+	data, err := os.ReadFile(b.path(SettingsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings
+	}
+	if b.failed(SettingsFile, err) {
+		return settings
+	}
+	if !b.decodeJSON(SettingsFile, data, settings) {
+		return DefaultSettings()
+	}
+	b.checkSettings(settings)
+	return settings
+}
+
+// decodeJSON decodes data, read from path, into v. Unknown fields are
+// errors, which are reported at the line they are on when it is known.
+func (b *builder) decodeJSON(path string, data []byte, v any) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		return true
+	}
+	line := 0
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		line = lineAt(data, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		line = lineAt(data, typeErr.Offset)
+	}
+	b.diags.Errorf(path, line, "%v", err)
+	return false
+}
+
+func (b *builder) checkSettings(s *Settings) {
+	if s.Title == "" {
+		b.diags.Errorf(SettingsFile, 0, "title must not be empty")
+	}
+	if s.BaseURL != "" {
+		if u, err := url.Parse(s.BaseURL); err != nil || !u.IsAbs() || u.Host == "" {
+			b.diags.Errorf(SettingsFile, 0, "baseURL %q must be an absolute http(s) URL", s.BaseURL)
+		}
+	}
+	if !languagePat.MatchString(s.Language) {
+		b.diags.Errorf(SettingsFile, 0, "language %q isn't a language tag like \"ru\" or \"pt-BR\"", s.Language)
+	}
+	if _, ok := styles.Registry[s.Style]; !ok {
+		b.diags.Errorf(SettingsFile, 0, "unknown chroma style %q", s.Style)
+	}
+	if _, ok := styles.Registry[s.DarkStyle]; s.DarkStyle != "" && !ok {
+		b.diags.Errorf(SettingsFile, 0, "unknown chroma style %q for darkStyle", s.DarkStyle)
+	}
+	for _, asset := range s.Assets {
+		if !b.exists("templates/" + asset) {
+			b.diags.Errorf(SettingsFile, 0, "asset %q not found in templates/", asset)
+		}
+	}
+	if u, err := url.Parse(s.Playground); err != nil || !u.IsAbs() {
+		b.diags.Errorf(SettingsFile, 0, "playground %q must be an absolute URL", s.Playground)
+	}
+	if s.OutDir == "" {
+		b.diags.Errorf(SettingsFile, 0, "outDir must not be empty")
+	}
+	for _, author := range s.Authors {
+		if author.Name == "" {
+			b.diags.Errorf(SettingsFile, 0, "authors need a name")
+		}
+	}
+	if len(s.Locales) > 0 && s.LanguageName == "" {
+		b.diags.Errorf(SettingsFile, 0, "languageName must not be empty when there are locales")
+	}
+	paths := make(map[string]bool)
+	for _, loc := range s.Locales {
+		if !languagePat.MatchString(loc.Language) {
+			b.diags.Errorf(SettingsFile, 0, "locale language %q isn't a language tag like \"en\" or \"pt-BR\"", loc.Language)
+		}
+		if loc.Name == "" || loc.Title == "" {
+			b.diags.Errorf(SettingsFile, 0, "locale %q needs a name and a title", loc.Language)
+		}
+		if loc.Dir == "" || !b.exists(filepath.Join(loc.Dir, "examples.txt")) {
+			b.diags.Errorf(SettingsFile, 0, "locale %q needs a dir with an examples.txt", loc.Language)
+		}
+		if !localePathPat.MatchString(loc.Path) || paths[loc.Path] {
+			b.diags.Errorf(SettingsFile, 0, "locale %q needs a unique path of lowercase words joined by dashes", loc.Language)
+		}
+		paths[loc.Path] = true
+	}
+}
+
+// lineAt returns the line of data that offset falls in.
+func lineAt(data []byte, offset int64) int {
+	return bytes.Count(data[:min(int(offset), len(data))], []byte("\n")) + 1
+}
@@ -0,0 +1,156 @@
+package site
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ShareBackend turns example code into the key of a Go Playground snippet,
+// which is used to build the "Run code" links.
+type ShareBackend interface {
+	Share(code string) (string, error)
+}
+
+// ErrPending is returned by backends that don't share anything right now.
+// The example is rendered without a working run link and its .hash file is
+// left alone, so a later online build picks the change up.
+var ErrPending = errors.New("playground share pending")
+
+// ErrStale is returned by CheckShare for every example whose .hash file no
+// longer matches its code.
+var ErrStale = errors.New("stale .hash file")
+
+// HTTPShare POSTs code to a playground's share endpoint. It's used both for
+// go.dev and for self-hosted instances of golang.org/x/playground.
+type HTTPShare struct {
+	Endpoint string
+	Client   *http.Client
+
+	// Attempts is the number of tries before giving up; Backoff is the
+	// wait before the second one, and grows linearly after that.
+	Attempts int
+	Backoff  time.Duration
+}
+
+// NewHTTPShare returns an HTTPShare for endpoint with the default timeout
+// and retries.
+func NewHTTPShare(endpoint string) *HTTPShare {
+	return &HTTPShare{
+		Endpoint: endpoint,
+		Client:   &http.Client{Timeout: 15 * time.Second},
+		Attempts: 3,
+		Backoff:  time.Second,
+	}
+}
+
+func (s *HTTPShare) Share(code string) (string, error) {
+	var err error
+	for attempt := 1; attempt <= max(s.Attempts, 1); attempt++ {
+		if attempt > 1 {
+			time.Sleep(s.Backoff * time.Duration(attempt-1))
+		}
+		var key string
+		key, err = s.post(code)
+		if err == nil {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("after %d attempts: %w", max(s.Attempts, 1), err)
+}
+
+func (s *HTTPShare) post(code string) (string, error) {
+	resp, err := s.Client.Post(s.Endpoint, "text/plain", strings.NewReader(code))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", s.Endpoint, resp.Status)
+	}
+	key := strings.TrimSpace(string(body))
+	if key == "" || strings.ContainsAny(key, "/ \n") {
+		return "", fmt.Errorf("%s: unexpected snippet key %q", s.Endpoint, key)
+	}
+	return key, nil
+}
+
+// CheckShare never shares; it reports every stale .hash file instead, so CI
+// can fail without touching the network.
+type CheckShare struct{}
+
+func (CheckShare) Share(code string) (string, error) {
+	return "", ErrStale
+}
+
+// OfflineShare never shares and marks run links as pending.
+type OfflineShare struct{}
+
+func (OfflineShare) Share(code string) (string, error) {
+	return "", ErrPending
+}
+
+// NewShareBackend returns the backend for a share mode as accepted by
+// tools/generate -share (go.dev, url, check or offline), along with the URL
+// prefix that snippet keys are appended to in run links. The check and
+// offline modes keep the run links of playground, go.dev if it's empty,
+// which the keys in the .hash files come from.
+func NewShareBackend(mode, playground string) (ShareBackend, string, error) {
+	if playground == "" && (mode == "check" || mode == "offline") {
+		playground = GoDevPlayground
+	}
+	switch mode {
+	case "go.dev":
+		return NewHTTPShare(GoDevPlayground + "/_/share"), DefaultPlayURL, nil
+	case "url":
+		// Keys in .hash files aren't tagged with the playground that issued
+		// them, so remove the .hash files when switching a site over.
+		if playground == "" {
+			return nil, "", errors.New("-share=url needs -playground to be set")
+		}
+		return NewHTTPShare(strings.TrimSuffix(playground, "/") + "/share"), PlayURL(playground), nil
+	case "check":
+		return CheckShare{}, PlayURL(playground), nil
+	case "offline":
+		return OfflineShare{}, PlayURL(playground), nil
+	}
+	return nil, "", fmt.Errorf("unknown -share mode %q, expected go.dev, url, check or offline", mode)
+}
+
+func (b *builder) parseHashFile(sourcePath string) (string, string) {
+	lines := b.readLines(sourcePath)
+	if len(lines) < 2 || lines[0] == "" || lines[1] == "" {
+		b.diags.Warnf(sourcePath, 1, "expected the code hash and the playground key on the first two lines, sharing the code again")
+		return "", ""
+	}
+	return lines[0], lines[1]
+}
+
+// resetURLHashFile shares code with the backend and records the new key in
+// the example's .hash file. It returns the key to use in the run link, which
+// is empty when the share is pending.
+func (b *builder) resetURLHashFile(codehash, code, sourcePath string) string {
+	b.logf("  Sharing code with the playground")
+	urlkey, err := b.cfg.Share.Share(code)
+	switch {
+	case errors.Is(err, ErrPending):
+		return ""
+	case errors.Is(err, ErrStale):
+		b.diags.Errorf(sourcePath, 1, "out of date with the example's code, rerun without -share=check")
+		return ""
+	case err != nil:
+		b.diags.Errorf(sourcePath, 0, "sharing with the playground: %v", err)
+		return ""
+	}
+	data := fmt.Sprintf("%s\n%s\n", codehash, urlkey)
+	b.failed(sourcePath, os.WriteFile(b.path(sourcePath), []byte(data), 0644))
+	return urlkey
+}
@@ -0,0 +1,147 @@
+package site
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// playgroundServer is a share endpoint that answers the first failures
+// requests with 503 and the rest with key.
+type playgroundServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+	code     string
+}
+
+func newPlaygroundServer(t *testing.T, failures int, key string) *playgroundServer {
+	s := &playgroundServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if r.Method != http.MethodPost || r.URL.Path != "/share" {
+			http.NotFound(w, r)
+			return
+		}
+		if s.requests <= failures {
+			http.Error(w, "failing on purpose", http.StatusServiceUnavailable)
+			return
+		}
+		s.code = string(body)
+		fmt.Fprintln(w, key)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHTTPShare(t *testing.T) {
+	tests := []struct {
+		failures, attempts int
+		key                string
+		want, wantErr      string
+		wantRequests       int
+	}{
+		{0, 3, "abc123", "abc123", "", 1},
+		{2, 3, "abc123", "abc123", "", 3},
+		{3, 3, "abc123", "", "after 3 attempts: http://", 3},
+		{0, 0, "abc123", "abc123", "", 1},
+		{1, 0, "abc123", "", "503 Service Unavailable", 1},
+		{0, 2, "a/b", "", `unexpected snippet key "a/b"`, 2},
+	}
+	for _, tt := range tests {
+		server := newPlaygroundServer(t, tt.failures, tt.key)
+		share := NewHTTPShare(server.URL + "/share")
+		share.Attempts, share.Backoff = tt.attempts, 0
+		got, err := share.Share("package main")
+		if got != tt.want || (err == nil) != (tt.wantErr == "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%d failures, %d attempts, key %q: Share = %q, %v; want %q, error %q", tt.failures, tt.attempts, tt.key, got, err, tt.want, tt.wantErr)
+		}
+		if server.requests != tt.wantRequests {
+			t.Errorf("%d failures, %d attempts, key %q: %d requests, want %d", tt.failures, tt.attempts, tt.key, server.requests, tt.wantRequests)
+		}
+		if tt.want != "" && server.code != "package main" {
+			t.Errorf("shared %q, want the code", server.code)
+		}
+	}
+}
+
+func TestNewShareBackend(t *testing.T) {
+	tests := []struct {
+		mode, playground string
+		wantEndpoint     string
+		wantPlayURL      string
+	}{
+		{"go.dev", GoDevPlayground, "https://go.dev/_/share", DefaultPlayURL},
+		{"url", "http://127.0.0.1:8001/", "http://127.0.0.1:8001/share", "http://127.0.0.1:8001/p/"},
+		{"check", GoDevPlayground, "", DefaultPlayURL},
+		{"offline", GoDevPlayground, "", DefaultPlayURL},
+		{"check", "http://127.0.0.1:8001", "", "http://127.0.0.1:8001/p/"},
+		{"offline", "http://127.0.0.1:8001", "", "http://127.0.0.1:8001/p/"},
+		{"offline", "", "", DefaultPlayURL},
+	}
+	for _, tt := range tests {
+		backend, playURL, err := NewShareBackend(tt.mode, tt.playground)
+		if err != nil {
+			t.Errorf("NewShareBackend(%q, %q): %v", tt.mode, tt.playground, err)
+			continue
+		}
+		endpoint := ""
+		if s, ok := backend.(*HTTPShare); ok {
+			endpoint = s.Endpoint
+		}
+		if endpoint != tt.wantEndpoint || playURL != tt.wantPlayURL {
+			t.Errorf("NewShareBackend(%q, %q) posts to %q with run links %q, want %q and %q", tt.mode, tt.playground, endpoint, playURL, tt.wantEndpoint, tt.wantPlayURL)
+		}
+	}
+	for _, mode := range []string{"url", "nope"} {
+		if _, _, err := NewShareBackend(mode, ""); err == nil {
+			t.Errorf("NewShareBackend(%q, \"\") succeeded", mode)
+		}
+	}
+}
+
+func TestShareModes(t *testing.T) {
+	const hashPath = "examples/hello/hello.hash"
+	const old = "0000\nold\n"
+	server := newPlaygroundServer(t, 0, "abc123")
+	tests := []struct {
+		share    ShareBackend
+		wantKey  string
+		wantHash string
+		wantDiag string
+	}{
+		{NewHTTPShare(server.URL + "/share"), "abc123", "1234\nabc123\n", ""},
+		{CheckShare{}, "", old, "out of date with the example's code"},
+		{OfflineShare{}, "", old, ""},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeFiles(t, root, hashPath, old)
+		diags := &Diagnostics{}
+		b := newBuilder(Config{Root: root, Share: tt.share, Diagnostics: diags, Settings: DefaultSettings()})
+		if key := b.resetURLHashFile("1234", "package main", hashPath); key != tt.wantKey {
+			t.Errorf("%T: key %q, want %q", tt.share, key, tt.wantKey)
+		}
+		data, err := os.ReadFile(filepath.Join(root, hashPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.wantHash {
+			t.Errorf("%T: .hash file is %q, want %q", tt.share, data, tt.wantHash)
+		}
+		if tt.wantDiag == "" {
+			checkDiags(t, diags)
+		} else {
+			checkDiags(t, diags, tt.wantDiag)
+		}
+	}
+}
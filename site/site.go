@@ -0,0 +1,479 @@
+// Package site builds the Go by Example website: it parses the annotated
+// programs in examples/ into segments of docs and code, renders them with
+// the templates in templates/ and writes the static site.
+//
+// A typical use loads the examples listed in examples.txt and renders them:
+//
+//	cfg := site.Config{Root: "."}
+//	examples, err := site.Load(cfg)
+//	if err != nil {
+//		// err lists every problem found, see Diagnostics.
+//	}
+//	err = site.Render(cfg, examples, "public")
+//
+// Build does the same incrementally, the way tools/generate runs it.
+package site
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config describes where the sources of a site are and how to build it. The
+// zero value builds the site in the current directory without sharing
+// anything with the Go Playground.
+type Config struct {
+	// Root is the directory that holds examples.txt, examples/ and
+	// templates/. Paths in diagnostics and in the build manifest are
+	// relative to it. Empty means the current directory.
+	Root string
+
+	// Share is used to get playground links for examples whose code
+	// changed. Nil leaves their run links pending, like OfflineShare.
+	Share ShareBackend
+
+	// PlayURL is the prefix that playground snippet keys are appended to in
+	// run links. Empty means the one of the configured playground.
+	PlayURL string
+
+	// Workers is the number of examples parsed and rendered concurrently.
+	// Zero or less means one.
+	Workers int
+
+	// Full makes Build ignore the manifest of the previous build and
+	// render everything.
+	Full bool
+
+	// History makes Build and Render read the git history of the examples
+	// for the changelog and the feed. Without it, or without a full
+	// history to read, Build keeps the changes of the previous build.
+	History bool
+
+	// Log receives progress messages when it's not nil.
+	Log io.Writer
+
+	// Diagnostics collects the problems found. Nil means a new collection
+	// for every call; pass one to see warnings as well as errors.
+	Diagnostics *Diagnostics
+
+	// Settings configures the site. Nil means reading site.json from Root.
+	Settings *Settings
+}
+
+// DefaultPlayURL is the prefix of run links on go.dev.
+const DefaultPlayURL = "https://go.dev/play/p/"
+
+// Templates are the files in templates/ that pages are rendered from.
+var Templates = []string{"index.tmpl", "example.tmpl", "404.tmpl", "footer.tmpl", "locales.tmpl", "changelog.tmpl", "feed.tmpl", "api.tmpl"}
+
+// Seg is a segment of an example
+type Seg struct {
+	Docs, DocsRendered              string
+	Code, CodeRendered, CodeForJs   string
+	CodeEmpty, CodeLeading, CodeRun bool
+
+	// Anchor is the ID of the segment's row on the page.
+	Anchor string
+
+	// goCode is set for segments of Go sources, as opposed to transcripts.
+	goCode bool
+
+	// codeLines are the indexes of the lines of the code in its file.
+	codeLines []int
+}
+
+// SiteConfig holds site-wide configuration passed to templates
+type SiteConfig struct {
+	CSSVersion    string
+	JSVersion     string
+	SearchVersion string
+	ChromaVersion string
+
+	// PlayURL is the prefix that playground snippet keys are appended to
+	// in run links.
+	PlayURL string
+
+	// AssetPrefix is the path from the pages to the shared assets: empty
+	// for the primary locale and "../" for the others.
+	AssetPrefix string
+
+	// The rest comes from site.json, see Settings. Title and Language are
+	// those of the page's locale.
+	Title      string
+	BaseURL    string
+	Language   string
+	Authors    []Author
+	SourceURL  string
+	LicenseURL string
+}
+
+// AuthorSep returns the text that goes before the i-th author when they are
+// listed as "A, B and C".
+func (s *SiteConfig) AuthorSep(i int) string {
+	switch {
+	case i == 0:
+		return ""
+	case i == len(s.Authors)-1:
+		return " and "
+	}
+	return ", "
+}
+
+// IndexData holds data for rendering the index page
+type IndexData struct {
+	Examples   []*Example
+	Sections   []*Section
+	Alternates []Alternate
+	Site       *SiteConfig
+
+	// URL is the canonical URL of the index, empty without a base URL.
+	URL string
+}
+
+// NotFoundData holds data for rendering the 404 page
+type NotFoundData struct {
+	Site *SiteConfig
+}
+
+// ChangelogData holds data for rendering the changelog page and the feed.
+type ChangelogData struct {
+	Changes []*Change
+	Site    *SiteConfig
+
+	// Updated is the time of the latest change, or of the build if there
+	// are none, as the feed needs one.
+	Updated string
+
+	// Base is the URL of the index page, which links in the feed are
+	// relative to: absolute with a base URL and empty otherwise.
+	Base string
+
+	// FeedID identifies the feed: its URL, or a UUID derived from the
+	// site's title without a base URL.
+	FeedID string
+}
+
+// APIIndexData holds data for rendering the API index: what the examples
+// use from the standard library and the language, and where.
+type APIIndexData struct {
+	// Packages are the standard library packages the examples use, and
+	// the builtins as the package "builtin", by import path.
+	Packages []*APIPackage
+
+	// Keywords are the keywords the examples use, but for package, import
+	// and func, which every program has.
+	Keywords []*APIName
+	Site     *SiteConfig
+}
+
+// APIPackage is a package in the API index, with the examples that import
+// it and the names in it that they use.
+type APIPackage struct {
+	// Path is the import path, which is also the ID of the package in
+	// the index, and DocURL its documentation on pkg.go.dev, with Synopsis
+	// the first sentence of it.
+	Path, DocURL, Synopsis string
+
+	Imports []*APIUse
+	Names   []*APIName
+}
+
+// APIName is a name in a package of the API index, like "Println" or
+// "WaitGroup.Add", or a keyword, with the examples that use it.
+type APIName struct {
+	// ID is the name's ID in the index: the import path and the name
+	// joined by a dot, or "keyword-" and the keyword. DocURL and Synopsis
+	// are empty for keywords.
+	Name, ID, DocURL, Synopsis string
+
+	Uses []*APIUse
+}
+
+// APIUse is an example that uses something in the API index, with the
+// segments that do.
+type APIUse struct {
+	Example *Example
+	Segs    []APISeg
+}
+
+// APISeg is a segment of an example: its anchor, and its place among the
+// example's segments, from 1.
+type APISeg struct {
+	Anchor string
+	N      int
+}
+
+// BookData holds data for rendering the single-page book, the pages and
+// package document of the EPUB book, and the files of the Markdown export,
+// whose segments have Markdown docs and fenced code.
+type BookData struct {
+	// Sections are the sections of examples.txt, or a single one without
+	// an ID and a title for a list without sections.
+	Sections []*BookSection
+	Site     *SiteConfig
+
+	// CSS is the stylesheet of the single page, which has no other files.
+	CSS string
+
+	// Chapter is the example that a page of the EPUB book renders, and ID
+	// and Modified the identifier of the EPUB book and the time it was
+	// written.
+	Chapter      *BookChapter
+	ID, Modified string
+}
+
+// BookSection is a section of the book, and Href the link to it.
+type BookSection struct {
+	ID, Title, Href string
+	Chapters        []*BookChapter
+}
+
+// BookChapter is an example in the book: its ID in the single page, the
+// link to it and its segments, with the links of their docs pointing into
+// the book.
+type BookChapter struct {
+	Example  *Example
+	ID, Href string
+	Segs     [][]*BookSeg
+}
+
+// BookSeg is a segment in the book, with the ID of its row and its docs
+// and code rendered.
+type BookSeg struct {
+	ID, Docs, Code string
+	CodeEmpty      bool
+}
+
+// Example is info extracted from an example file
+type Example struct {
+	// ID is a stable slug used for URLs, directory names and output filenames,
+	// e.g. "hello-world", "values", "time-formatting-parsing".
+	ID string
+
+	// Title is the human-readable, potentially localized title that is
+	// rendered on the page (HTML <title>, <h2>, index list, navigation, etc.).
+	Title string
+
+	// Name is kept for backwards compatibility; it mirrors Title so existing
+	// template usages or code that still refer to .Name continue to work.
+	Name                        string
+	GoCode, GoCodeHash, URLHash string
+	Segs                        [][]*Seg
+	PrevExample                 *Example
+	NextExample                 *Example
+
+	// Section is the section of examples.txt the example is listed in, nil
+	// for a list without sections.
+	Section *Section
+
+	// Meta is the example's metadata, empty without a meta.json, and
+	// Related the examples it names as related that the locale lists.
+	Meta    Meta
+	Related []*Example
+
+	// MinGo is the oldest Go release the example's code builds with, as
+	// worked out from the code, empty for an example without Go code.
+	MinGo GoRequirement
+
+	// Packages are the import paths of the standard library packages the
+	// code imports, in order, which are also their IDs in the API index.
+	Packages []string
+
+	// Alternates are the versions of the example in the other locales,
+	// empty for a site with a single one.
+	Alternates []Alternate
+	Site       *SiteConfig
+
+	// Description is the start of the first docs as plain text, and
+	// Modified the date the sources last changed, if known.
+	Description string
+	Modified    string
+
+	// URL is the canonical URL of the page, empty without a base URL, and
+	// JSONLD its structured data.
+	URL    string
+	JSONLD string
+
+	// position is the example's place in examples.txt, from 1.
+	position int
+}
+
+// NextSection returns the section after the example's, for readers to
+// skip ahead to, or nil if there is none or the next example starts it.
+func (e *Example) NextSection() *Section {
+	if e.Section == nil || e.Section.Next == nil || e.NextExample == e.Section.Next.Examples[0] {
+		return nil
+	}
+	return e.Section.Next
+}
+
+// Section is a group of examples that examples.txt lists under a "## Title"
+// line, like a chapter of a book.
+type Section struct {
+	// ID is the anchor of the section on the index page, derived from its
+	// title.
+	ID    string
+	Title string
+
+	Examples []*Example
+
+	// Next is the section after this one, nil for the last.
+	Next *Section
+}
+
+// builder carries the configuration and the diagnostics of one call into
+// the package. All paths it takes are relative to the configured root;
+// locale selects the examples and templates it reads, see Locale, and
+// catalog translates their docs.
+type builder struct {
+	cfg      Config
+	diags    *Diagnostics
+	settings *Settings
+	locale   *Locale
+	catalog  *catalog
+	goCheck  *goChecker
+}
+
+func newBuilder(cfg Config) *builder {
+	if cfg.Share == nil {
+		cfg.Share = OfflineShare{}
+	}
+	b := &builder{cfg: cfg, diags: cfg.Diagnostics, settings: cfg.Settings, goCheck: &goChecker{}}
+	if b.diags == nil {
+		b.diags = &Diagnostics{}
+	}
+	if b.settings == nil {
+		b.settings = b.loadSettings()
+	}
+	if b.cfg.PlayURL == "" {
+		b.cfg.PlayURL = PlayURL(b.settings.Playground)
+	}
+	b.locale = b.settings.primaryLocale()
+	return b
+}
+
+// PlayURL returns the prefix of run links for a playground base URL.
+func PlayURL(playground string) string {
+	if playground == GoDevPlayground {
+		return DefaultPlayURL
+	}
+	return strings.TrimSuffix(playground, "/") + "/p/"
+}
+
+// path turns a path relative to the root into one usable for file access.
+func (b *builder) path(rel string) string {
+	if b.cfg.Root == "" {
+		return rel
+	}
+	return filepath.Join(b.cfg.Root, rel)
+}
+
+func (b *builder) logf(format string, args ...any) {
+	if b.cfg.Log != nil {
+		fmt.Fprintf(b.cfg.Log, format+"\n", args...)
+	}
+}
+
+// failed records err, if there is one, as an error in path and reports
+// whether there was one.
+func (b *builder) failed(path string, err error) bool {
+	if err != nil {
+		b.diags.Errorf(path, 0, "%v", err)
+		return true
+	}
+	return false
+}
+
+// err returns the diagnostics as an error if any of them is an error.
+func (b *builder) err() error {
+	if b.diags.Errors() > 0 {
+		return b.diags
+	}
+	return nil
+}
+
+func (b *builder) isDir(path string) bool {
+	fileStat, err := os.Stat(b.path(path))
+	if b.failed(path, err) {
+		return false
+	}
+	return fileStat.IsDir()
+}
+
+func (b *builder) exists(path string) bool {
+	_, err := os.Stat(b.path(path))
+	return err == nil
+}
+
+// readFile returns the contents of path, or "" after recording a diagnostic
+// if it can't be read.
+func (b *builder) readFile(path string) string {
+	bytes, err := os.ReadFile(b.path(path))
+	if b.failed(path, err) {
+		return ""
+	}
+	return string(bytes)
+}
+
+func (b *builder) readLines(path string) []string {
+	src := b.readFile(path)
+	return strings.Split(src, "\n")
+}
+
+// glob returns the paths matching pattern, relative to the root.
+func (b *builder) glob(pattern string) []string {
+	paths, err := filepath.Glob(b.path(pattern))
+	if b.failed(pattern, err) {
+		return nil
+	}
+	if b.cfg.Root != "" {
+		for i, path := range paths {
+			if rel, err := filepath.Rel(b.cfg.Root, path); err == nil {
+				paths[i] = rel
+			}
+		}
+	}
+	return paths
+}
+
+func (b *builder) fileHash(path string) string {
+	content := b.readFile(path)
+	return sha1Sum(content)[:8]
+}
+
+func (b *builder) siteConfig() *SiteConfig {
+	site := &SiteConfig{
+		CSSVersion:    b.fileHash("templates/site.css"),
+		JSVersion:     b.fileHash("templates/site.js"),
+		SearchVersion: b.fileHash("templates/search.js"),
+		ChromaVersion: sha1Sum(b.chromaCSS())[:8],
+		PlayURL:       b.cfg.PlayURL,
+		Title:         b.locale.Title,
+		BaseURL:       b.settings.BaseURL,
+		Language:      b.locale.Language,
+		Authors:       b.settings.Authors,
+		SourceURL:     b.settings.SourceURL,
+		LicenseURL:    b.settings.LicenseURL,
+	}
+	if !b.isPrimary() {
+		site.AssetPrefix = "../"
+	}
+	return site
+}
+
+func sha1Sum(s string) string {
+	h := sha1.New()
+	h.Write([]byte(s))
+	bs := h.Sum(nil)
+	return fmt.Sprintf("%x", bs)
+}
+
+func debug(msg string) {
+	if os.Getenv("DEBUG") == "1" {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+}
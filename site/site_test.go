@@ -0,0 +1,22 @@
+package site
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkLoad parses and renders the full examples.txt with growing
+// numbers of workers; compare workers=1 with the rest for the speedup on
+// the current machine.
+func BenchmarkLoad(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := Config{Root: "..", Share: CheckShare{}, Workers: workers}
+			for b.Loop() {
+				if _, err := Load(cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
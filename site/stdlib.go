@@ -0,0 +1,144 @@
+package site
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// StdlibFile is the name of the file in the root that keeps what pages use
+// from the api/ files and the docs of the standard library, so that they
+// come out the same with any GOROOT. Build adds what new code looks up
+// there, and a full build drops what nothing looks up anymore.
+const StdlibFile = "stdlib.json"
+
+// stdlibData is what was looked up in GOROOT for the examples: the release
+// that added a package, by import path, and a name, by "path.Name" or
+// "path.Type.Member", "" for none; the synopsis of a doc comment, by
+// "path" or "path#Name" like in docURL, nil for a name that isn't
+// documented; and whether any package has a name at its top level.
+type stdlibData struct {
+	Packages     map[string]string  `json:"packages"`
+	Names        map[string]string  `json:"names"`
+	Docs         map[string]*string `json:"docs"`
+	PackageLevel map[string]bool    `json:"packageLevel"`
+}
+
+func newStdlibData() *stdlibData {
+	return &stdlibData{
+		Packages:     make(map[string]string),
+		Names:        make(map[string]string),
+		Docs:         make(map[string]*string),
+		PackageLevel: make(map[string]bool),
+	}
+}
+
+// loadStdlib reads StdlibFile, unless it was read already. Without one, the
+// data is empty.
+func (c *goChecker) loadStdlib(b *builder) {
+	if c.stdlib != nil {
+		return
+	}
+	c.stdlib, c.used = newStdlibData(), newStdlibData()
+	data, err := os.ReadFile(b.path(StdlibFile))
+	if errors.Is(err, fs.ErrNotExist) || b.failed(StdlibFile, err) {
+		return
+	}
+	b.decodeJSON(StdlibFile, data, c.stdlib)
+	c.saved = string(data)
+}
+
+// stdlibHash returns the hash of StdlibFile as it was read or last saved,
+// which the keys of the pages that use it include.
+func (c *goChecker) stdlibHash() string {
+	return sha1Sum(c.saved)
+}
+
+// saveStdlib writes what was looked up in GOROOT to StdlibFile, if that adds
+// to it or, in a full build, drops something from it, and reports whether
+// it did.
+func (b *builder) saveStdlib() bool {
+	c := b.goCheck
+	if c.stdlib == nil || c.err != nil {
+		return false
+	}
+	s := c.stdlib
+	if b.cfg.Full {
+		s = c.used
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if b.failed(StdlibFile, err) {
+		return false
+	}
+	data = append(data, '\n')
+	if string(data) == c.saved {
+		return false
+	}
+	b.logf("Writing %s", StdlibFile)
+	if b.failed(StdlibFile, os.WriteFile(b.path(StdlibFile), data, 0644)) {
+		return false
+	}
+	c.saved = string(data)
+	return true
+}
+
+// lookup returns the value of key in m, which read gets and adds when it's
+// missing, and adds it to used.
+func lookup[V any](m, used map[string]V, key string, read func() V) V {
+	v, ok := m[key]
+	if !ok {
+		v = read()
+		m[key] = v
+	}
+	used[key] = v
+	return v
+}
+
+// pkgVersion returns the release that added a package of the standard
+// library, or "" for a package that isn't one. c.mu must be held.
+func (c *goChecker) pkgVersion(path string) string {
+	return lookup(c.stdlib.Packages, c.used.Packages, path, func() string {
+		return c.api.pkgs[path]
+	})
+}
+
+// nameVersion returns the release that added a name of the standard
+// library, "path.Name" or "path.Type.Member", or "" if none did. c.mu must
+// be held.
+func (c *goChecker) nameVersion(name string) string {
+	return lookup(c.stdlib.Names, c.used.Names, name, func() string {
+		return c.api.names[name]
+	})
+}
+
+// docSynopsis returns the synopsis of the doc comment of a name in a
+// standard library package, or of the package for an empty key, and
+// whether the package documents the name, see packageDocs. c.mu must be
+// held.
+func (c *goChecker) docSynopsis(path, key string) (string, bool) {
+	s := lookup(c.stdlib.Docs, c.used.Docs, strings.TrimPrefix(docURL(path, key), GoDocURL), func() *string {
+		if s, ok := c.packageDocs(path)[key]; ok {
+			return &s
+		}
+		return nil
+	})
+	if s == nil {
+		return "", false
+	}
+	return *s, true
+}
+
+// packageLevel reports whether a package of the standard library has an
+// exported name at its top level. c.mu must be held.
+func (c *goChecker) packageLevel(name string) bool {
+	return lookup(c.stdlib.PackageLevel, c.used.PackageLevel, name, func() bool {
+		for path := range c.api.pkgs {
+			if _, ok := c.api.names[path+"."+name]; ok {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,88 @@
+package site
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildStdlib(t *testing.T) {
+	root, out := t.TempDir(), t.TempDir()
+	copyTemplates(t, root)
+	writeFiles(t, root,
+		"examples.txt", "hello|Hello\n",
+		"examples/hello/hello.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+	build := func(full bool) {
+		t.Helper()
+		if err := Build(Config{Root: root, Settings: DefaultSettings(), Full: full}, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() *stdlibData {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(root, StdlibFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newStdlibData()
+		if err := json.Unmarshal(data, s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	write := func(s *stdlibData) {
+		t.Helper()
+		data, _ := json.MarshalIndent(s, "", "  ")
+		writeFiles(t, root, StdlibFile, string(data)+"\n")
+	}
+
+	manifest := func() string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, ManifestFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	build(false)
+	s := read()
+	if s.Packages["fmt"] != "go1" || s.Docs["fmt#Println"] == nil {
+		t.Fatalf("%s has no fmt.Println: %+v", StdlibFile, s)
+	}
+	// The keys count the file that the build wrote.
+	first := manifest()
+	build(false)
+	if manifest() != first {
+		t.Errorf("a build without changes changed the manifest")
+	}
+
+	// Pages show what the file has rather than what GOROOT has, are
+	// rendered again when it changes, and only a full build drops what
+	// nothing looked up.
+	recorded := "Println prints what the file has."
+	s.Docs["fmt#Println"] = &recorded
+	s.Names["fmt.Unused"] = "go1"
+	write(s)
+	build(false)
+	page, err := os.ReadFile(filepath.Join(out, "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `title="`+recorded+`"`) {
+		t.Errorf("page has no title %q:\n%s", recorded, page)
+	}
+	if s := read(); s.Names["fmt.Unused"] != "go1" {
+		t.Errorf("a build dropped fmt.Unused from %s", StdlibFile)
+	}
+	build(true)
+	s = read()
+	if _, ok := s.Names["fmt.Unused"]; ok {
+		t.Errorf("a full build kept fmt.Unused in %s", StdlibFile)
+	}
+	if got := s.Docs["fmt#Println"]; got == nil || *got != recorded {
+		t.Errorf("a full build changed fmt#Println in %s", StdlibFile)
+	}
+}
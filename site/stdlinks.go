@@ -0,0 +1,422 @@
+package site
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GoDocURL is the prefix of links to the documentation of standard library
+// packages.
+const GoDocURL = "https://pkg.go.dev/"
+
+var (
+	// docCodePat matches the code spans of rendered docs, and the links
+	// around them, which are left alone.
+	docCodePat = regexp.MustCompile(`(?s)<a\s.*?</a>|<code>([^<]*)</code>`)
+
+	// docNamePat matches the code spans that may name something of the
+	// standard library, like "Println", "time.Now" or "ctx.Done()".
+	docNamePat = regexp.MustCompile(`^[A-Za-z_]\w*(?:\.[A-Za-z_]\w*){0,2}$`)
+)
+
+// stdRef is a use of a standard library name in a Go file: where its
+// identifier is, on a line counted from 0 and at a column in bytes counted
+// from 0 once tabs are spaces like in Seg.Code, and what it names.
+type stdRef struct {
+	line, col, len int
+
+	// path is the import path of the package, and key the name in it,
+	// "Name" or "Type.Member", or "" for the package itself.
+	path, key string
+
+	// title is the synopsis of the name's doc comment.
+	title string
+}
+
+// docURL returns the link to the documentation of a name in a standard
+// library package, or of the package for an empty key.
+func docURL(path, key string) string {
+	if key == "" {
+		return GoDocURL + path
+	}
+	return GoDocURL + path + "#" + key
+}
+
+// docLink returns the opening tag of a link to the documentation of a
+// name, with its synopsis as the title if there is one.
+func docLink(path, key, title string) string {
+	tag := `<a class="godoc" href="` + html.EscapeString(docURL(path, key)) + `"`
+	if title != "" {
+		tag += ` title="` + html.EscapeString(title) + `"`
+	}
+	return tag + ">"
+}
+
+// newStdRef returns the reference to a name at a position of a Go file
+// with the source src. c.mu must be held.
+func (c *goChecker) newStdRef(fset *token.FileSet, p token.Pos, src string, n int, path, key string) stdRef {
+	pos := fset.Position(p)
+	prefix := src[pos.Offset-(pos.Column-1) : pos.Offset]
+	ref := stdRef{
+		line: pos.Line - 1,
+		col:  len(prefix) + 3*strings.Count(prefix, "\t"),
+		len:  n,
+		path: path,
+		key:  key,
+	}
+	if path != "" {
+		ref.title, _ = c.docSynopsis(path, key)
+	}
+	return ref
+}
+
+// collectRefs adds the standard library names that the selectors of a
+// file use to code: qualified identifiers, whose package names count too,
+// and the methods and fields of standard library types. Its imports of
+// standard library packages and the builtins it uses go into the other
+// references. c.mu must be held.
+func (c *goChecker) collectRefs(file *ast.File, src string, info *types.Info, code *codeInfo) {
+	name := c.fset.File(file.Pos()).Name()
+	add := func(id *ast.Ident, path, key string) {
+		code.refs[name] = append(code.refs[name], c.newStdRef(c.fset, id.Pos(), src, len(id.Name), path, key))
+	}
+	for _, spec := range file.Imports {
+		if path := strings.Trim(spec.Path.Value, `"`); c.pkgVersion(path) != "" {
+			code.others[name] = append(code.others[name], c.newStdRef(c.fset, spec.Path.Pos(), src, len(spec.Path.Value), path, ""))
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			switch obj := info.Uses[id].(type) {
+			case *types.Builtin:
+				code.others[name] = append(code.others[name], c.newStdRef(c.fset, id.Pos(), src, len(id.Name), "builtin", obj.Name()))
+			case *types.TypeName:
+				if obj.Parent() == types.Universe {
+					code.others[name] = append(code.others[name], c.newStdRef(c.fset, id.Pos(), src, len(id.Name), "builtin", obj.Name()))
+				}
+			}
+			return true
+		}
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if s := info.Selections[sel]; s != nil {
+			obj := s.Obj()
+			if !obj.Exported() || obj.Pkg() == nil || c.pkgVersion(obj.Pkg().Path()) == "" {
+				return true
+			}
+			var named *types.Named
+			switch obj := obj.(type) {
+			case *types.Func:
+				if recv := obj.Origin().Signature().Recv(); recv != nil {
+					named = namedType(recv.Type())
+				}
+			case *types.Var:
+				// Fields of embedded structs belong to types the
+				// selection doesn't name.
+				if len(s.Index()) == 1 {
+					named = namedType(s.Recv())
+				}
+			}
+			if named != nil && named.Obj().Exported() {
+				add(sel.Sel, obj.Pkg().Path(), named.Origin().Obj().Name()+"."+obj.Name())
+			}
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pkgName, ok := info.Uses[x].(*types.PkgName)
+		if !ok || c.pkgVersion(pkgName.Imported().Path()) == "" {
+			return true
+		}
+		path := pkgName.Imported().Path()
+		code.imports[x.Name] = path
+		add(x, path, "")
+		if obj := info.Uses[sel.Sel]; obj != nil && obj.Exported() {
+			add(sel.Sel, path, obj.Name())
+		}
+		return true
+	})
+}
+
+// collectKeywords adds the keywords of a file to the other references of
+// code, but for those every program has.
+func (c *goChecker) collectKeywords(file *ast.File, src string, code *codeInfo) {
+	name := c.fset.File(file.Pos()).Name()
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile(name, -1, len(src)), []byte(src), nil, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch {
+		case !tok.IsKeyword(), tok == token.PACKAGE, tok == token.IMPORT, tok == token.FUNC:
+			continue
+		}
+		code.others[name] = append(code.others[name], c.newStdRef(fset, pos, src, len(lit), "", lit))
+	}
+}
+
+// packageDocs returns the synopses of the doc comments of a standard
+// library package, read from its sources in GOROOT: the package's by "",
+// and its exported names', or the builtins', by "Name" and "Type.Member".
+// A package that can't be read has none. c.mu must be held.
+func (c *goChecker) packageDocs(path string) map[string]string {
+	if docs, ok := c.docs[path]; ok {
+		return docs
+	}
+	docs := make(map[string]string)
+	c.docs[path] = docs
+	bp, err := build.Default.Import(path, "", 0)
+	if err != nil {
+		return docs
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return docs
+		}
+		files = append(files, file)
+	}
+	// The builtins are documented as the unexported names of a package.
+	var mode doc.Mode
+	if path == "builtin" {
+		mode = doc.AllDecls
+	}
+	p, err := doc.NewFromFiles(fset, files, path, mode)
+	if err != nil {
+		return docs
+	}
+
+	docs[""] = p.Synopsis(p.Doc)
+	values := func(values []*doc.Value) {
+		for _, v := range values {
+			for _, name := range v.Names {
+				docs[name] = p.Synopsis(v.Doc)
+			}
+		}
+	}
+	funcs := func(prefix string, funcs []*doc.Func) {
+		for _, f := range funcs {
+			docs[prefix+f.Name] = p.Synopsis(f.Doc)
+		}
+	}
+	values(p.Consts)
+	values(p.Vars)
+	funcs("", p.Funcs)
+	for _, t := range p.Types {
+		docs[t.Name] = p.Synopsis(t.Doc)
+		values(t.Consts)
+		values(t.Vars)
+		funcs("", t.Funcs)
+		funcs(t.Name+".", t.Methods)
+		// Fields and interface methods, which have a doc comment above
+		// them or a comment after them.
+		for _, spec := range t.Decl.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || ts.Name.Name != t.Name {
+				continue
+			}
+			var fields *ast.FieldList
+			switch typ := ts.Type.(type) {
+			case *ast.StructType:
+				fields = typ.Fields
+			case *ast.InterfaceType:
+				fields = typ.Methods
+			default:
+				continue
+			}
+			for _, field := range fields.List {
+				text := field.Doc.Text()
+				if text == "" {
+					text = field.Comment.Text()
+				}
+				for _, name := range field.Names {
+					if name.IsExported() || mode == doc.AllDecls {
+						docs[t.Name+"."+name.Name] = p.Synopsis(text)
+					}
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// synopsis returns the synopsis of the doc comment of a name in a standard
+// library package, see packageDocs.
+func (c *goChecker) synopsis(path, key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, _ := c.docSynopsis(path, key)
+	return s
+}
+
+// linkStd links the standard library names in the code of the segments of
+// an example's Go files, and in their docs, to their documentation on
+// pkg.go.dev, with their synopses as titles.
+func (b *builder) linkStd(info *codeInfo, goSegs map[string][]*Seg) {
+	var all []stdRef
+	for name, segs := range goSegs {
+		refs := info.refs[name]
+		all = append(all, refs...)
+		for _, seg := range segs {
+			if seg.CodeRendered != "" {
+				seg.CodeRendered = linkCode(seg, refs)
+			}
+		}
+	}
+	c := b.goCheck
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, segs := range goSegs {
+		for _, seg := range segs {
+			if seg.DocsRendered != "" {
+				seg.DocsRendered = c.linkDocs(seg.DocsRendered, info, all)
+			}
+		}
+	}
+}
+
+// linkCode returns the rendered code of a segment with the uses of
+// standard library names among refs linked.
+func linkCode(seg *Seg, refs []stdRef) string {
+	// The offsets in seg.Code of the lines of the file that it has.
+	starts := make(map[int]int)
+	off := 0
+	for k, line := range strings.Split(seg.Code, "\n") {
+		if k < len(seg.codeLines) {
+			starts[seg.codeLines[k]] = off
+		}
+		off += len(line) + 1
+	}
+	spans := make(map[int]stdRef)
+	for _, ref := range refs {
+		if start, ok := starts[ref.line]; ok {
+			spans[start+ref.col] = ref
+		}
+	}
+	if len(spans) == 0 {
+		return seg.CodeRendered
+	}
+	return linkTokens(seg.CodeRendered, spans)
+}
+
+// linkTokens wraps the tokens in chroma's HTML that are as long as the
+// spans at their offsets in the code in links. Chroma puts every token in
+// an element of its own, so a span that is a whole token is a run of text
+// between two tags; offsets count the bytes of the code, which entities
+// stand for.
+func linkTokens(rendered string, spans map[int]stdRef) string {
+	var sb strings.Builder
+	off := 0
+	for rendered != "" {
+		if rendered[0] == '<' {
+			end := strings.IndexByte(rendered, '>') + 1
+			if end == 0 {
+				end = len(rendered)
+			}
+			sb.WriteString(rendered[:end])
+			rendered = rendered[end:]
+			continue
+		}
+		text := rendered
+		if i := strings.IndexByte(rendered, '<'); i >= 0 {
+			text = rendered[:i]
+		}
+		rendered = rendered[len(text):]
+		n := len(html.UnescapeString(text))
+		if ref, ok := spans[off]; ok && ref.len == n {
+			fmt.Fprintf(&sb, "%s%s</a>", docLink(ref.path, ref.key, ref.title), text)
+		} else {
+			sb.WriteString(text)
+		}
+		off += n
+	}
+	return sb.String()
+}
+
+// linkDocs links the code spans of rendered docs that name something of
+// the standard library: a package the example uses, by name or import
+// path, a name qualified by one, like "time.Now", or a name the code uses
+// that no other name it uses could be, like "Println" or "Done()". Spans
+// in links stay as they are. c.mu must be held.
+func (c *goChecker) linkDocs(rendered string, info *codeInfo, refs []stdRef) string {
+	return docCodePat.ReplaceAllStringFunc(rendered, func(m string) string {
+		if !strings.HasPrefix(m, "<code>") {
+			return m
+		}
+		text := html.UnescapeString(docCodePat.FindStringSubmatch(m)[1])
+		path, key, ok := c.resolveDocName(text, info, refs)
+		if !ok {
+			return m
+		}
+		title, _ := c.docSynopsis(path, key)
+		return docLink(path, key, title) + m + "</a>"
+	})
+}
+
+// resolveDocName returns the package and name that a code span of docs
+// names, see linkDocs.
+func (c *goChecker) resolveDocName(text string, info *codeInfo, refs []stdRef) (path, key string, ok bool) {
+	for _, path := range info.imports {
+		if text == path {
+			return path, "", true
+		}
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(text, "()"), "(...)")
+	call, text := name != text, name
+	if !docNamePat.MatchString(text) {
+		return "", "", false
+	}
+	if pkg, name, qualified := strings.Cut(text, "."); !qualified {
+		if path, ok := info.imports[text]; ok {
+			return path, "", true
+		}
+	} else if path, ok := info.imports[pkg]; ok {
+		_, ok := c.docSynopsis(path, name)
+		return path, name, ok
+	}
+
+	// An unqualified name, which is the whole key of a name the code
+	// uses or, failing that, its member. Unless it's a call, a member
+	// needs a name that no package of the standard library has: `Context`
+	// may well be the type rather than the method of http.Request that
+	// the code calls.
+	for _, exact := range []bool{true, false} {
+		if !exact && !call && c.packageLevel(text) {
+			break
+		}
+		found := 0
+		for _, ref := range refs {
+			match := ref.key == text
+			if !exact {
+				match = strings.HasSuffix(ref.key, "."+text)
+			}
+			if match && (found == 0 || ref.path != path || ref.key != key) {
+				path, key = ref.path, ref.key
+				found++
+			}
+		}
+		if found > 0 {
+			return path, key, found == 1
+		}
+	}
+	return "", "", false
+}
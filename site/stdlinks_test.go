@@ -0,0 +1,83 @@
+package site
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkCode(t *testing.T) {
+	b := newBuilder(Config{Root: t.TempDir(), Settings: DefaultSettings()})
+	seg := &Seg{Code: "func main() {\n    fmt.Println(\"<\", time.Now)", codeLines: []int{4, 5}}
+	seg.CodeRendered = b.chromaFormat(seg.Code, "main.go")
+	got := linkCode(seg, []stdRef{
+		{line: 5, col: 4, len: 3, path: "fmt", title: "Package fmt implements formatted I/O."},
+		{line: 5, col: 8, len: 7, path: "fmt", key: "Println"},
+		// Not a whole token.
+		{line: 5, col: 22, len: 2, path: "time"},
+		// Not a line of the segment.
+		{line: 3, col: 0, len: 4, path: "time"},
+	})
+	for _, want := range []string{
+		`<a class="godoc" href="https://pkg.go.dev/fmt" title="Package fmt implements formatted I/O.">fmt</a>`,
+		`<a class="godoc" href="https://pkg.go.dev/fmt#Println">Println</a>`,
+		`&#34;&lt;&#34;`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("linked code has no %s:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "<a "); n != 2 {
+		t.Errorf("linked code has %d links, want 2:\n%s", n, got)
+	}
+}
+
+func TestResolveDocName(t *testing.T) {
+	c := &goChecker{
+		api: &apiVersions{
+			names: map[string]string{"context.Context": "go1.7", "time.Now": "go1"},
+			pkgs:  map[string]string{"context": "go1.7", "net/http": "go1", "time": "go1"},
+		},
+		docs: map[string]map[string]string{
+			"net/http": {"": "Package http provides HTTP client and server implementations."},
+			"time":     {"": "Package time provides functionality for measuring and displaying time.", "Now": "Now returns the current local time."},
+		},
+		stdlib: newStdlibData(),
+		used:   newStdlibData(),
+	}
+	info := &codeInfo{imports: map[string]string{"time": "time", "http": "net/http"}}
+	refs := []stdRef{
+		{path: "time", key: "Now"},
+		{path: "time", key: "Time.Unix"},
+		{path: "time", key: "Time.Unix"},
+		{path: "net/http", key: "Request.Context"},
+		{path: "net/http", key: "Request.Close"},
+		{path: "time", key: "Timer.Stop"},
+		{path: "time", key: "Ticker.Stop"},
+	}
+	tests := []struct {
+		text, path, key string
+	}{
+		{"net/http", "net/http", ""},
+		{"time", "time", ""},
+		{"time.Now", "time", "Now"},
+		{"time.Now()", "time", "Now"},
+		{"time.Never", "", ""},
+		{"Now", "time", "Now"},
+		{"Unix", "time", "Time.Unix"},
+		{"Request.Close", "net/http", "Request.Close"},
+		{"Context", "", ""},
+		{"Context()", "net/http", "Request.Context"},
+		{"Stop", "", ""},
+		{"t.Run", "", ""},
+		{"a + b", "", ""},
+	}
+	for _, tt := range tests {
+		path, key, ok := c.resolveDocName(tt.text, info, refs)
+		if !ok {
+			path, key = "", ""
+		}
+		if path != tt.path || key != tt.key {
+			t.Errorf("resolveDocName(%q) = %q, %q, want %q, %q", tt.text, path, key, tt.path, tt.key)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sync compares the examples with those of an upstream checkout or snapshot
+// of Go by Example, such as the English original of a translation. Both are
+// split into segments the way pages are, and the segments of every file are
+// lined up by their code, so that translated doc comments don't count as
+// differences.
+//
+// Every difference is reported as a warning in the diagnostics: segments
+// whose code changed, segments and files added or removed upstream, and
+// upstream examples missing from examples.txt. With apply, files whose only
+// differences are changed code get the upstream code, keeping their own doc
+// comments; Sync returns the paths of the files it rewrote.
+func Sync(cfg Config, upstream string, apply bool) ([]string, error) {
+	b := newBuilder(cfg)
+	up := newBuilder(Config{Root: upstream, Diagnostics: b.diags, Settings: b.settings})
+	if !up.exists("examples.txt") {
+		b.diags.Errorf(upstream, 0, "no examples.txt, expected a checkout of Go by Example")
+		return nil, b.err()
+	}
+
+	examples := b.readExampleList()
+	listed := make(map[string]bool)
+	for _, example := range examples {
+		listed[example.ID] = true
+	}
+	for _, example := range up.readUpstreamList() {
+		if !listed[example.ID] {
+			b.diags.Warnf("examples.txt", 0, "upstream example %q (%s) is missing", example.ID, example.Title)
+		}
+	}
+
+	var applied []string
+	for _, example := range examples {
+		dir := "examples/" + example.ID
+		if !up.exists(dir) {
+			continue
+		}
+		for _, upPath := range up.glob(dir + "/*") {
+			if ext := filepath.Ext(upPath); ext == ".go" || ext == ".sh" {
+				if ours := filepath.Join(dir, filepath.Base(upPath)); !b.exists(ours) {
+					b.diags.Warnf(dir, 0, "file %s added upstream", filepath.Base(upPath))
+				}
+			}
+		}
+		for _, path := range b.glob(dir + "/*") {
+			if ext := filepath.Ext(path); ext != ".go" && ext != ".sh" {
+				continue
+			}
+			if !up.exists(path) {
+				b.diags.Warnf(path, 0, "file isn't upstream")
+				continue
+			}
+			if b.syncFile(up, path, apply) {
+				applied = append(applied, path)
+			}
+		}
+	}
+	return applied, b.err()
+}
+
+// readUpstreamList reads the examples.txt of an upstream tree, which may
+// list just titles as the original does, or slugs and titles like ours.
+func (b *builder) readUpstreamList() []*Example {
+	var examples []*Example
+	for _, raw := range b.readLines("examples.txt") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if id, title, ok := strings.Cut(line, "|"); ok {
+			examples = append(examples, &Example{ID: strings.TrimSpace(id), Title: strings.TrimSpace(title)})
+			continue
+		}
+		examples = append(examples, &Example{ID: titleSlug(line), Title: line})
+	}
+	return examples
+}
+
+// titleSlug derives an example's ID from its title, as the original
+// generator does.
+func titleSlug(title string) string {
+	id := strings.ToLower(title)
+	id = strings.Replace(id, " ", "-", -1)
+	id = strings.Replace(id, "/", "-", -1)
+	id = strings.Replace(id, "'", "", -1)
+	return dashPat.ReplaceAllString(id, "-")
+}
+
+// segOp is a step in lining up two files' segments: a segment of ours and
+// one of upstream with the same code, or one missing on either side (-1).
+type segOp struct {
+	ours, theirs int
+}
+
+// alignSegs lines up two lists of segment codes with a longest common
+// subsequence, so that only the segments that really differ are left
+// unmatched.
+func alignSegs(ours, theirs []string) []segOp {
+	n, m := len(ours), len(theirs)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if ours[i] == theirs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []segOp
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && ours[i] == theirs[j]:
+			ops = append(ops, segOp{i, j})
+			i, j = i+1, j+1
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, segOp{i, -1})
+			i++
+		default:
+			ops = append(ops, segOp{-1, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// sourceSegs reads a source file and splits it into segments, returning its
+// lines, the segments and the code of each.
+func (b *builder) sourceSegs(path string) ([]string, []*segSpan, []string) {
+	lines := b.readLines(path)
+	spans := segmentLines(lines)
+	codes := make([]string, len(spans))
+	for i, span := range spans {
+		codes[i] = joinLines(lines, span.code, func(line string) string {
+			return strings.TrimRight(line, " \t")
+		})
+	}
+	return lines, spans, codes
+}
+
+// spanLine returns the line number that a diagnostic about span points at:
+// its first code line, or its first doc line for a segment without code.
+func spanLine(span *segSpan) int {
+	if len(span.code) > 0 {
+		return span.code[0] + 1
+	}
+	return span.docs[0] + 1
+}
+
+// syncFile compares a source file with its upstream version and reports the
+// differences. With apply, it writes the upstream code into the file when
+// code changed but no segment was added or removed, and reports whether it
+// did.
+func (b *builder) syncFile(up *builder, path string, apply bool) bool {
+	lines, spans, codes := b.sourceSegs(path)
+	upLines, upSpans, upCodes := up.sourceSegs(path)
+	upPath := filepath.Join(up.cfg.Root, path)
+
+	// A run of unmatched segments between two matched ones is a change of
+	// code where both sides have one, and an addition or removal beyond
+	// that.
+	var changed [][2]int
+	addedOrRemoved := false
+	ops := alignSegs(codes, upCodes)
+	for k := 0; k < len(ops); {
+		if ops[k].ours >= 0 && ops[k].theirs >= 0 {
+			k++
+			continue
+		}
+		var ours, theirs []int
+		for ; k < len(ops) && (ops[k].ours < 0 || ops[k].theirs < 0); k++ {
+			if ops[k].ours >= 0 {
+				ours = append(ours, ops[k].ours)
+			} else {
+				theirs = append(theirs, ops[k].theirs)
+			}
+		}
+		for len(ours) > 0 && len(theirs) > 0 {
+			changed = append(changed, [2]int{ours[0], theirs[0]})
+			b.diags.Warnf(path, spanLine(spans[ours[0]]), "code differs from upstream %s:%d", upPath, spanLine(upSpans[theirs[0]]))
+			ours, theirs = ours[1:], theirs[1:]
+		}
+		for _, i := range ours {
+			addedOrRemoved = true
+			b.diags.Warnf(path, spanLine(spans[i]), "segment removed upstream")
+		}
+		for _, j := range theirs {
+			addedOrRemoved = true
+			b.diags.Warnf(path, 0, "segment added upstream at %s:%d", upPath, spanLine(upSpans[j]))
+		}
+	}
+
+	if !apply || len(changed) == 0 {
+		return false
+	}
+	if addedOrRemoved {
+		b.diags.Warnf(path, 0, "not applied, segments were added or removed upstream and need translating")
+		return false
+	}
+	// Replace the code lines of the changed segments from the last one up,
+	// so that the line indexes of the others stay valid.
+	for k := len(changed) - 1; k >= 0; k-- {
+		span, upSpan := spans[changed[k][0]], upSpans[changed[k][1]]
+		code := make([]string, len(upSpan.code))
+		for i, line := range upSpan.code {
+			code[i] = upLines[line]
+		}
+		var from, to int
+		if len(span.code) > 0 {
+			from, to = span.code[0], span.code[len(span.code)-1]+1
+		} else {
+			from = span.docs[len(span.docs)-1] + 1
+			to = from
+		}
+		lines = append(lines[:from], append(code, lines[to:]...)...)
+	}
+	data := strings.Join(lines, "\n")
+	return !b.failed(path, os.WriteFile(b.path(path), []byte(data), 0644))
+}
@@ -0,0 +1,139 @@
+package site
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAlignSegs(t *testing.T) {
+	tests := []struct {
+		ours, theirs string
+		want         string
+	}{
+		{"", "", ""},
+		{"a b c", "a b c", "0=0 1=1 2=2"},
+		{"a b c", "a x c", "0=0 1- -1 2=2"},
+		{"a b c", "a c", "0=0 1- 2=1"},
+		{"a c", "a b c", "0=0 -1 1=2"},
+		{"a b", "", "0- 1-"},
+		{"", "a b", "-0 -1"},
+		{"a b c d", "b d e", "0- 1=0 2- 3=1 -2"},
+		{"a a b", "a b b", "0=0 1- 2=1 -2"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, op := range alignSegs(strings.Fields(tt.ours), strings.Fields(tt.theirs)) {
+			switch {
+			case op.ours < 0:
+				got = append(got, fmt.Sprintf("-%d", op.theirs))
+			case op.theirs < 0:
+				got = append(got, fmt.Sprintf("%d-", op.ours))
+			default:
+				got = append(got, fmt.Sprintf("%d=%d", op.ours, op.theirs))
+			}
+		}
+		if s := strings.Join(got, " "); s != tt.want {
+			t.Errorf("alignSegs(%q, %q) = %s, want %s", tt.ours, tt.theirs, s, tt.want)
+		}
+	}
+}
+
+func TestSyncFile(t *testing.T) {
+	const path = "examples/hello/hello.go"
+	ours := "// Пример.\npackage main\n\nimport \"fmt\"\n\n" +
+		"// Печатаем.\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+	tests := []struct {
+		name, upstream string
+		want           string
+		wantApplied    bool
+		wantDiags      []string
+	}{
+		{
+			name:     "same code",
+			upstream: "// Example.\npackage main\n\nimport \"fmt\"\n\n// Print.\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n",
+			want:     ours,
+		},
+		{
+			name:     "changed code",
+			upstream: "// Example.\npackage main\n\nimport \"fmt\"\n\n// Print.\nfunc main() {\n\tfmt.Println(\"hello\")\n\tfmt.Println(\"world\")\n}\n",
+			want: "// Пример.\npackage main\n\nimport \"fmt\"\n\n" +
+				"// Печатаем.\nfunc main() {\n\tfmt.Println(\"hello\")\n\tfmt.Println(\"world\")\n}\n",
+			wantApplied: true,
+			wantDiags:   []string{"hello.go:7: warning: code differs from upstream"},
+		},
+		{
+			name: "added segment",
+			upstream: "// Example.\npackage main\n\nimport \"fmt\"\n\n// Print.\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n\n" +
+				"// Help.\nfunc help() {}\n",
+			want: ours,
+			wantDiags: []string{
+				"hello.go:7: warning: code differs from upstream",
+				"warning: segment added upstream at",
+				"warning: not applied, segments were added or removed upstream",
+			},
+		},
+		{
+			name:      "removed segment",
+			upstream:  "// Example.\npackage main\n\nimport \"fmt\"\n",
+			want:      ours,
+			wantDiags: []string{"hello.go:7: warning: segment removed upstream"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, upstream := t.TempDir(), t.TempDir()
+			writeFiles(t, root, path, ours)
+			writeFiles(t, upstream, path, tt.upstream)
+			diags := &Diagnostics{}
+			b := newBuilder(Config{Root: root, Diagnostics: diags, Settings: DefaultSettings()})
+			up := newBuilder(Config{Root: upstream, Diagnostics: diags, Settings: b.settings})
+			if applied := b.syncFile(up, path, true); applied != tt.wantApplied {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+			checkWarnings(t, diags, tt.wantDiags...)
+			data, err := os.ReadFile(filepath.Join(root, path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file after applying is\n%s\nwant\n%s", data, tt.want)
+			}
+			if !tt.wantApplied {
+				return
+			}
+			// The applied file has upstream's code, so the next sync finds
+			// nothing to do.
+			diags = &Diagnostics{}
+			b.diags, up.diags = diags, diags
+			if b.syncFile(up, path, true) {
+				t.Errorf("applied again")
+			}
+			checkWarnings(t, diags)
+		})
+	}
+}
+
+// checkWarnings fails unless the diagnostics are warnings with the wanted
+// messages, in any order.
+func checkWarnings(t *testing.T, diags *Diagnostics, want ...string) {
+	t.Helper()
+	var got []string
+	for _, d := range diags.List() {
+		got = append(got, d.String())
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			found = found || strings.Contains(g, w)
+		}
+		if !found {
+			t.Errorf("no diagnostic %q", w)
+		}
+	}
+	if len(got) != len(want) || diags.Errors() > 0 {
+		t.Errorf("got %d diagnostics, want %d warnings:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+}
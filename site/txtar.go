@@ -0,0 +1,79 @@
+package site
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// playgroundFile is a file of a program shared with the playground, named
+// relative to the example's directory.
+type playgroundFile struct {
+	name, data string
+}
+
+// dataFiles returns the files of an example's directory that aren't its
+// sources, its .hash file or its metadata, such as a go.mod or the files
+// its code reads or embeds, relative to the directory and in lexical
+// order.
+func (b *builder) dataFiles(dir string) []string {
+	var names []string
+	root := b.path(dir)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == MetaFile {
+			return nil
+		}
+		if !strings.Contains(name, "/") {
+			switch filepath.Ext(name) {
+			case ".go", ".sh", ".hash":
+				return nil
+			}
+		}
+		names = append(names, name)
+		return nil
+	})
+	b.failed(dir, err)
+	return names
+}
+
+// playgroundCode returns the code shared with the playground for an
+// example: the source of its Go file as it is, or, when the example has
+// several Go files, a go.mod or data files, all of them in the txtar format
+// that the playground reads as a multi-file program.
+func (b *builder) playgroundCode(dir string, goFiles []playgroundFile) string {
+	data := b.dataFiles(dir)
+	switch {
+	case len(goFiles) == 0:
+		return ""
+	case len(goFiles) == 1 && len(data) == 0:
+		return goFiles[0].data
+	}
+	files := append([]playgroundFile{}, goFiles...)
+	for _, name := range data {
+		files = append(files, playgroundFile{name, b.readFile(filepath.Join(dir, name))})
+	}
+	return formatTxtar(files)
+}
+
+// formatTxtar formats files as a txtar archive, ending every file with a
+// newline.
+func formatTxtar(files []playgroundFile) string {
+	var sb strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&sb, "-- %s --\n", f.name)
+		sb.WriteString(f.data)
+		if f.data != "" && !strings.HasSuffix(f.data, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,36 @@
+// Extracts the doc comments of the examples into a gettext PO catalog, one
+// entry per segment with the context "<example ID>:<segment index>":
+//
+//	$ tools/extract po/en.po
+//
+// An existing catalog is updated in place: translations are kept, entries
+// whose source changed are marked fuzzy and the translations of removed
+// segments are kept as obsolete "#~" entries. A locale in site.json with "catalog": "po/en.po" is then
+// rendered from the root examples with the translated docs. The work is
+// done by site.Extract.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mmcgrana/gobyexample/site"
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: tools/extract CATALOG.po")
+		os.Exit(2)
+	}
+
+	diags := &site.Diagnostics{}
+	stats, err := site.Extract(site.Config{Diagnostics: diags}, flag.Arg(0))
+	diags.Print(os.Stderr, false)
+	if err != nil {
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d entries, %d new, %d fuzzy, %d untranslated, %d obsolete\n",
+		flag.Arg(0), stats.Entries, stats.New, stats.Fuzzy, stats.Untranslated, stats.Obsolete)
+}
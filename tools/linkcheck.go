@@ -0,0 +1,55 @@
+// Checks the links in the doc comments of the examples and in the site
+// generated into the outDir of site.json, or into the directory given as
+// the argument: links between examples, to their segments and to assets
+// must all lead somewhere. With -external, links to other sites are
+// requested too. The work is done by site.CheckLinks.
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/mmcgrana/gobyexample/site"
+)
+
+func main() {
+	external := flag.Bool("external", false, "also request the links to other sites")
+	timeout := flag.Duration("timeout", 15*time.Second, "timeout of each request, with -external")
+	workers := flag.Int("j", runtime.NumCPU(), "number of external links requested concurrently")
+	asJSON := flag.Bool("json", false, "print diagnostics as JSON to stdout")
+	flag.Parse()
+
+	diags := &site.Diagnostics{}
+	out := os.Stderr
+	if *asJSON {
+		out = os.Stdout
+	}
+	settings, err := site.LoadSettings(".")
+	if err != nil {
+		err.(*site.Diagnostics).Print(out, *asJSON)
+		os.Exit(1)
+	}
+
+	siteDir := settings.OutDir
+	if flag.NArg() > 0 {
+		siteDir = flag.Arg(0)
+	}
+	var client *http.Client
+	if *external {
+		client = &http.Client{Timeout: *timeout}
+	}
+
+	cfg := site.Config{Workers: *workers, Diagnostics: diags, Settings: settings}
+	if len(os.Getenv("VERBOSE")) > 0 {
+		cfg.Log = os.Stdout
+	}
+	err = site.CheckLinks(cfg, siteDir, client)
+
+	diags.Print(out, *asJSON)
+	if err != nil {
+		os.Exit(1)
+	}
+}
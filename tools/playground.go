@@ -0,0 +1,72 @@
+// A local stand-in for the Go Playground's share API, for trying out
+// `tools/generate -share=url` without sending anything to go.dev:
+//
+//	$ tools/playground &
+//	$ tools/generate -share=url -playground=http://127.0.0.1:8001
+//
+// Shared snippets are kept in memory and can be read back at /p/<key>.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8001", "address to listen on")
+	failures := flag.Int("fail", 0, "answer the first n share requests with 503, to exercise retries")
+	flag.Parse()
+
+	var (
+		mu       sync.Mutex
+		snippets = make(map[string]string)
+		failed   int
+	)
+
+	http.HandleFunc("/share", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST only", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failed < *failures {
+			failed++
+			http.Error(w, "failing on purpose", http.StatusServiceUnavailable)
+			return
+		}
+		// Like the real playground, the key only depends on the content.
+		sum := sha256.Sum256(body)
+		key := strings.ReplaceAll(base64.URLEncoding.EncodeToString(sum[:])[:11], "-", "_")
+		snippets[key] = string(body)
+		log.Printf("shared %d bytes as %s", len(body), key)
+		fmt.Fprint(w, key)
+	})
+
+	http.HandleFunc("/p/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		code, ok := snippets[strings.TrimPrefix(r.URL.Path, "/p/")]
+		mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, code)
+	})
+
+	fmt.Printf("Serving a stand-in playground at http://%s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
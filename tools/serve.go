@@ -3,11 +3,19 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
+
+	"github.com/mmcgrana/gobyexample/site"
 )
 
 func main() {
 	port := "8000"
-	publicDir := "public"
-	fmt.Printf("Serving Go by Example at http://127.0.0.1:%s\n", port)
+	settings, err := site.LoadSettings(".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	publicDir := settings.OutDir
+	fmt.Printf("Serving %s at http://127.0.0.1:%s\n", settings.Title, port)
 	http.ListenAndServe(":"+port, http.FileServer(http.Dir(publicDir)))
 }
@@ -0,0 +1,44 @@
+// Compares the examples with an upstream checkout of Go by Example, such as
+// the English original of this translation:
+//
+//	$ git clone https://github.com/mmcgrana/gobyexample /tmp/upstream
+//	$ tools/sync /tmp/upstream
+//
+// Segments whose code changed, segments and files added or removed upstream
+// and upstream examples missing from examples.txt are reported as warnings.
+// With -apply, files whose code changed get the upstream code while keeping
+// their doc comments. The exit status is 1 only for errors, such as an
+// unreadable upstream. The work is done by site.Sync.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mmcgrana/gobyexample/site"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "write code-only changes from upstream into the examples")
+	asJSON := flag.Bool("json", false, "print the differences as JSON to stdout")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: tools/sync [-apply] [-json] UPSTREAM_DIR")
+		os.Exit(2)
+	}
+
+	diags := &site.Diagnostics{}
+	out := os.Stderr
+	if *asJSON {
+		out = os.Stdout
+	}
+	applied, err := site.Sync(site.Config{Diagnostics: diags}, flag.Arg(0), *apply)
+	diags.Print(out, *asJSON)
+	for _, path := range applied {
+		fmt.Fprintf(os.Stderr, "sync: updated %s, rerun tools/generate to share it\n", path)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -1,4 +1,4 @@
-// Uploads the generated site from the public/ directory to the S3 bucket from
+// Uploads the generated site from the outDir of site.json to the S3 bucket from
 // which it's served.
 // To invoke this program, the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
 // env vars have to be set appropriately, and the -region and -bucket flags
@@ -8,6 +8,7 @@ package main
 import (
 	"context"
 	"flag"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -15,6 +16,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/mmcgrana/gobyexample/site"
 )
 
 // guessContentType guesses the HTTP content type appropriate for the given
@@ -27,6 +30,16 @@ func guessContentType(filename string) string {
 		return "image/png"
 	case ".css":
 		return "text/css"
+	case ".js":
+		return "text/javascript"
+	case ".json":
+		return "application/json"
+	case ".xml":
+		return "application/xml"
+	case ".atom":
+		return "application/atom+xml"
+	case ".txt":
+		return "text/plain; charset=utf-8"
 	default:
 		return "text/html"
 	}
@@ -48,36 +61,44 @@ func main() {
 
 	client := s3.NewFromConfig(cfg)
 
-	// The whole contents of the public/ directory are uploaded. This code assumes
-	// the directory structure is flat - there are no subdirectories.
-	publicDir := "./public/"
-	c, err := os.ReadDir(publicDir)
+	settings, err := site.LoadSettings(".")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	for _, entry := range c {
-		if !entry.IsDir() {
-			file, err := os.Open(filepath.Join(publicDir, entry.Name()))
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer file.Close()
+	// The whole contents of the output directory are uploaded, including
+	// the subdirectories that other locales are generated into.
+	publicDir := settings.OutDir
+	err = filepath.WalkDir(publicDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		key, err := filepath.Rel(publicDir, path)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
 
-			contentType := guessContentType(entry.Name())
-			log.Printf("Uploading %s (%s)", entry.Name(), contentType)
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
 
-			cfg := &s3.PutObjectInput{
-				Bucket:      bucket,
-				Key:         aws.String(entry.Name()),
-				Body:        file,
-				ContentType: aws.String(contentType),
-			}
+		contentType := guessContentType(entry.Name())
+		log.Printf("Uploading %s (%s)", key, contentType)
 
-			_, err = client.PutObject(context.TODO(), cfg)
-			if err != nil {
-				log.Fatal(err)
-			}
+		cfg := &s3.PutObjectInput{
+			Bucket:      bucket,
+			Key:         aws.String(key),
+			Body:        file,
+			ContentType: aws.String(contentType),
 		}
+
+		_, err = client.PutObject(context.TODO(), cfg)
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,350 @@
+// Verifies that the shell transcripts in examples/*/*.sh match what the
+// programs actually print. Every `$ go run X.go` command in a transcript is
+// executed in the example's directory and its combined stdout and stderr are
+// compared line by line against the transcript lines that the site's shell
+// lexer classifies as output.
+//
+// Nondeterministic output (timestamps, PIDs, timings, goroutine scheduling)
+// is handled with per-example directives read from verify.txt; see the
+// comment at the top of that file for the syntax.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+
+	"github.com/mmcgrana/gobyexample/site"
+)
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func readLines(path string) []string {
+	srcBytes, err := os.ReadFile(path)
+	check(err)
+	return strings.Split(string(srcBytes), "\n")
+}
+
+func verbose() bool {
+	return len(os.Getenv("VERBOSE")) > 0
+}
+
+// goRunPat matches the commands that get executed; an optional `time` prefix,
+// environment assignments and a pipe feeding stdin are allowed.
+var goRunPat = regexp.MustCompile(`(^|\|\s*|^time\s+|^(\w+=\S*\s+)+)go run\s`)
+
+// rules holds the verify.txt directives for a single example.
+type rules struct {
+	skip      string
+	lines     []*regexp.Regexp
+	ignore    []*regexp.Regexp
+	unordered bool
+}
+
+// command is a `$ go run` line from a transcript together with the output
+// lines that follow it.
+type command struct {
+	path   string
+	line   int
+	cmd    string
+	output []outputLine
+}
+
+type outputLine struct {
+	line int
+	text string
+}
+
+func parseRules(path string) map[string]*rules {
+	all := make(map[string]*rules)
+	for i, raw := range readLines(path) {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "|", 3)
+		if len(parts) != 3 {
+			panic(fmt.Sprintf("%s:%d: expected 'slug|directive|argument'", path, i+1))
+		}
+		id, directive, arg := parts[0], parts[1], parts[2]
+		r := all[id]
+		if r == nil {
+			r = &rules{}
+			all[id] = r
+		}
+		switch directive {
+		case "skip":
+			r.skip = arg
+		case "line":
+			r.lines = append(r.lines, regexp.MustCompile(arg))
+		case "ignore":
+			r.ignore = append(r.ignore, regexp.MustCompile(arg))
+		case "unordered":
+			r.unordered = true
+		default:
+			panic(fmt.Sprintf("%s:%d: unknown directive %q", path, i+1, directive))
+		}
+	}
+	return all
+}
+
+// parseTranscript tokenises the code part of a transcript with the site's
+// shell lexer and collects the `go run` commands with their
+// expected output. Doc comment lines are blanked out first so that token
+// positions still map to lines in the original file.
+func parseTranscript(path string) []*command {
+	lines := readLines(path)
+	code := make([]string, len(lines))
+	for i, line := range lines {
+		if !site.IsDocLine(line) {
+			code[i] = line
+		}
+	}
+	iterator, err := site.SimpleShellOutputLexer.Tokenise(nil, strings.Join(code, "\n"))
+	check(err)
+
+	var (
+		cmds    []*command
+		current *command
+		lineNo  = 1
+		prompt  string
+	)
+	for _, tok := range iterator.Tokens() {
+		switch {
+		case tok.Type == chroma.GenericPrompt:
+			prompt = tok.Value
+		case tok.Type == chroma.Text && prompt != "" && tok.Value != "\n":
+			text := strings.TrimSpace(tok.Value)
+			if prompt == "$" {
+				current = nil
+				if goRunPat.MatchString(text) {
+					current = &command{path: path, line: lineNo, cmd: text}
+					cmds = append(cmds, current)
+				}
+			} else if current != nil {
+				// A `>` line right after a `go run` is never a shell
+				// continuation in these transcripts; it's the program
+				// echoing a command it is about to spawn.
+				current.output = append(current.output, outputLine{lineNo, "> " + text})
+			}
+			prompt = ""
+		case tok.Type == chroma.GenericOutput && current != nil:
+			text := strings.TrimRight(tok.Value, " \t\n")
+			current.output = append(current.output, outputLine{lineNo, text})
+		}
+		lineNo += strings.Count(tok.Value, "\n")
+	}
+	return cmds
+}
+
+func matchesAny(pats []*regexp.Regexp, s string) bool {
+	for _, pat := range pats {
+		if pat.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineMatches reports whether an actual output line satisfies an expected
+// one: literally, as a prefix when the transcript abbreviates the line with a
+// trailing "...", or because both match the same placeholder pattern.
+func lineMatches(r *rules, expected, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(expected, "..."); ok && strings.HasPrefix(actual, prefix) {
+		return true
+	}
+	for _, pat := range r.lines {
+		if pat.MatchString(expected) && pat.MatchString(actual) {
+			return true
+		}
+	}
+	return false
+}
+
+func run(c *command, timeout time.Duration) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "bash", "-c", c.cmd)
+	cmd.Dir = filepath.Dir(c.path)
+	// Run the command in its own process group so that a timeout also stops
+	// any programs it started, and don't wait on pipes they keep open.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Non-zero exit codes are part of several transcripts ("exit status 3")
+	// and get compared like any other output, so the error is dropped here.
+	cmd.Run()
+	// Stop anything the command left running in the background.
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	if ctx.Err() != nil {
+		return []string{fmt.Sprintf("<timed out after %s>", timeout)}
+	}
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// compare returns a description of every mismatch between the expected
+// output of c and the actual lines.
+func compare(c *command, r *rules, actual []string) []string {
+	var kept []string
+	for _, line := range actual {
+		if !matchesAny(r.ignore, line) {
+			kept = append(kept, line)
+		}
+	}
+	expected := make([]outputLine, 0, len(c.output))
+	for _, ol := range c.output {
+		if ol.text != "" {
+			expected = append(expected, ol)
+		}
+	}
+
+	var problems []string
+	if r.unordered {
+		used := make([]bool, len(kept))
+	outer:
+		for _, ol := range expected {
+			for j, line := range kept {
+				if !used[j] && lineMatches(r, ol.text, line) {
+					used[j] = true
+					continue outer
+				}
+			}
+			problems = append(problems, fmt.Sprintf("%s:%d: missing %q", c.path, ol.line, ol.text))
+		}
+		for j, line := range kept {
+			if !used[j] {
+				problems = append(problems, fmt.Sprintf("%s:%d: unexpected %q", c.path, c.line, line))
+			}
+		}
+		return problems
+	}
+
+	for i := 0; i < len(expected) || i < len(kept); i++ {
+		switch {
+		case i >= len(kept):
+			problems = append(problems, fmt.Sprintf("%s:%d: missing %q", c.path, expected[i].line, expected[i].text))
+		case i >= len(expected):
+			problems = append(problems, fmt.Sprintf("%s:%d: unexpected %q", c.path, c.line, kept[i]))
+		case !lineMatches(r, expected[i].text, kept[i]):
+			problems = append(problems, fmt.Sprintf("%s:%d: expected %q, got %q", c.path, expected[i].line, expected[i].text, kept[i]))
+		}
+	}
+	return problems
+}
+
+func verifyExample(id string, r *rules, timeout time.Duration) []string {
+	if r.skip != "" {
+		if verbose() {
+			fmt.Printf("Skipping %s: %s\n", id, r.skip)
+		}
+		return nil
+	}
+	paths, err := filepath.Glob("examples/" + id + "/*.sh")
+	check(err)
+	var problems []string
+	for _, path := range paths {
+		for _, c := range parseTranscript(path) {
+			if verbose() {
+				fmt.Printf("Running %s:%d: %s\n", c.path, c.line, c.cmd)
+			}
+			problems = append(problems, compare(c, r, run(c, timeout))...)
+		}
+	}
+	return problems
+}
+
+func exampleIDs() []string {
+	examples, err := site.LoadList(site.Config{})
+	check(err)
+	var ids []string
+	for _, example := range examples {
+		ids = append(ids, example.ID)
+	}
+	return ids
+}
+
+func main() {
+	runPat := flag.String("run", "", "only verify examples whose slug matches this regexp")
+	timeout := flag.Duration("timeout", 30*time.Second, "time limit for a single command")
+	jobs := flag.Int("j", runtime.NumCPU(), "number of examples verified concurrently")
+	flag.Parse()
+
+	allRules := parseRules("verify.txt")
+	var ids []string
+	for _, id := range exampleIDs() {
+		if *runPat == "" || regexp.MustCompile(*runPat).MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	results := make([][]string, len(ids))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				r := allRules[ids[i]]
+				if r == nil {
+					r = &rules{}
+				}
+				results[i] = verifyExample(ids[i], r, *timeout)
+			}
+		}()
+	}
+	for i := range ids {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	var failed []string
+	for i, problems := range results {
+		for _, p := range problems {
+			fmt.Println("verify: " + p)
+		}
+		if len(problems) > 0 {
+			failed = append(failed, ids[i])
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		fmt.Printf("verify: %d example(s) differ from their transcripts: %s\n", len(failed), strings.Join(failed, ", "))
+		os.Exit(1)
+	}
+}